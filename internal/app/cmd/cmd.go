@@ -79,6 +79,65 @@ func Execute(ctx context.Context) {
 	cacheCmd.Flags().Uint16VarP(&cacheArgs.Port, "port", "p", 0, "Port of the cache server")
 	rootCmd.AddCommand(cacheCmd)
 
+	// ./act_runner gc
+	var gcArgs gcArgs
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove unused action clones, cache entries and archived logs according to the gc config",
+		Long: "Remove unused action clones, cache entries and archived logs according to the gc config.\n" +
+			"It refuses to remove anything while the runner daemon with the same registration file is running, except with --dry-run.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: runGC(&configFile, &gcArgs),
+	}
+	gcCmd.PersistentFlags().BoolVar(&gcArgs.DryRun, "dry-run", false, "Only show what would be removed")
+	gcCmd.AddCommand(&cobra.Command{
+		Use:   "images",
+		Short: "Remove unused docker images according to the gc.images config",
+		Long: "Remove unused docker images according to the gc.images config.\n" +
+			"It refuses to remove anything while the runner daemon with the same registration file is running, except with --dry-run.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: runGCImages(&configFile, &gcArgs),
+	})
+	rootCmd.AddCommand(gcCmd)
+
+	// ./act_runner import-actions
+	var importActionsArgs importActionsArgs
+	importActionsCmd := &cobra.Command{
+		Use:   "import-actions ARCHIVE...",
+		Short: "Import actions from tar archives into the action store of the offline mode",
+		Long: "Import actions from tar archives, which could be gzipped, into the action store of the offline mode.\n" +
+			"An archive is laid out like the store, with directories like \"<owner>/<repo>/<ref>\",\n" +
+			"or it's the source archive of a single action given by --action.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: runImportActions(&configFile, &importActionsArgs),
+	}
+	importActionsCmd.Flags().StringVar(&importActionsArgs.Action, "action", "", "The action which the archive contains, like \"actions/checkout@v4\"")
+	rootCmd.AddCommand(importActionsCmd)
+
+	// ./act_runner mirror-sync
+	var mirrorSyncArgs mirrorSyncArgs
+	mirrorSyncCmd := &cobra.Command{
+		Use:   "mirror-sync [ACTION...]",
+		Short: "Clone actions into the action cache in advance, through the action rewrites of the config",
+		Long: "Clone actions, like \"actions/checkout@v4\", and the actions used by the workflows in the given directories\n" +
+			"into the action cache shared by jobs, through the action rewrites of the config.",
+		RunE: runMirrorSync(&configFile, &mirrorSyncArgs),
+	}
+	mirrorSyncCmd.Flags().StringSliceVar(&mirrorSyncArgs.Workflows, "workflows", nil, "The directories of workflow files, like \".gitea/workflows\"")
+	mirrorSyncCmd.Flags().StringVar(&mirrorSyncArgs.DefaultURL, "default-actions-url", "https://github.com", "The instance of the actions used without a URL, like the DEFAULT_ACTIONS_URL of Gitea")
+	rootCmd.AddCommand(mirrorSyncCmd)
+
+	// ./act_runner doctor
+	var doctorArgs doctorArgs
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment of the runner and report the problems found",
+		Args:  cobra.MaximumNArgs(0),
+		RunE:  runDoctor(&configFile, &doctorArgs),
+	}
+	doctorCmd.Flags().BoolVar(&doctorArgs.JSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+
 	// hide completion command
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 
@@ -13,6 +13,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/mattn/go-isatty"
@@ -24,7 +25,10 @@ import (
 	"gitea.com/gitea/act_runner/internal/pkg/client"
 	"gitea.com/gitea/act_runner/internal/pkg/config"
 	"gitea.com/gitea/act_runner/internal/pkg/envcheck"
+	"gitea.com/gitea/act_runner/internal/pkg/imagegc"
 	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/lockfile"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
 	"gitea.com/gitea/act_runner/internal/pkg/ver"
 )
 
@@ -46,6 +50,13 @@ func runDaemon(ctx context.Context, daemArgs *daemonArgs, configFile *string) fu
 			return fmt.Errorf("failed to load registration file: %w", err)
 		}
 
+		// the gc commands refuse to remove anything while the daemon holds the lock, since they can't tell what its tasks use
+		lock, err := lockfile.Shared(daemonLockFile(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", daemonLockFile(cfg), err)
+		}
+		defer lock.Unlock()
+
 		lbls := reg.Labels
 		if len(cfg.Runner.Labels) > 0 {
 			lbls = cfg.Runner.Labels
@@ -64,13 +75,36 @@ func runDaemon(ctx context.Context, daemArgs *daemonArgs, configFile *string) fu
 			log.Warn("no labels configured, runner may not be able to pick up jobs")
 		}
 
+		if !slices.Equal(reg.Labels, ls.ToStrings()) {
+			reg.Labels = ls.ToStrings()
+			if err := config.SaveRegistration(cfg.Runner.File, reg); err != nil {
+				return fmt.Errorf("failed to save runner config: %w", err)
+			}
+			log.Infof("labels updated to: %v", reg.Labels)
+		}
+
+		if cfg.Runner.AutoLabels {
+			// the detected labels are not saved to the registration file,
+			// so they follow the host rather than the last run
+			if detected := labels.Detect(cfg.Runner.AutoLabelsPrefix, ls); detected != nil {
+				log.Infof("detected host labels: %v", detected.Names())
+				ls = ls.Merge(detected)
+				reg.Labels = ls.ToStrings()
+			} else {
+				log.Warn("auto_labels is ignored, since the runner has no host label and the detected labels would run jobs on the host")
+			}
+		}
+
+		var gate poll.Gate
 		if ls.RequireDocker() {
-			dockerSocketPath, err := getDockerSocketPath(cfg.Container.DockerHost)
+			dockerSocketPath, err := waitForDocker(ctx, cfg.Container.DockerHost, cfg.Container.DockerWaitTimeout)
 			if err != nil {
 				return err
 			}
-			if err := envcheck.CheckIfDockerRunning(ctx, dockerSocketPath); err != nil {
-				return err
+			if cfg.Container.DockerPingInterval > 0 {
+				monitor := envcheck.NewDockerMonitor(dockerSocketPath, cfg.Container.DockerPingInterval, cfg.Container.DockerStateFile)
+				go monitor.Run(ctx)
+				gate = monitor
 			}
 			// if dockerSocketPath passes the check, override DOCKER_HOST with dockerSocketPath
 			os.Setenv("DOCKER_HOST", dockerSocketPath)
@@ -89,14 +123,6 @@ func runDaemon(ctx context.Context, daemArgs *daemonArgs, configFile *string) fu
 			}
 		}
 
-		if !slices.Equal(reg.Labels, ls.ToStrings()) {
-			reg.Labels = ls.ToStrings()
-			if err := config.SaveRegistration(cfg.Runner.File, reg); err != nil {
-				return fmt.Errorf("failed to save runner config: %w", err)
-			}
-			log.Infof("labels updated to: %v", reg.Labels)
-		}
-
 		cli := client.New(
 			reg.Address,
 			cfg.Runner.Insecure,
@@ -105,7 +131,52 @@ func runDaemon(ctx context.Context, daemArgs *daemonArgs, configFile *string) fu
 			ver.Version(),
 		)
 
-		runner := run.NewRunner(cfg, reg, cli)
+		registries, err := registry.FromConfig(&cfg.Container)
+		if err != nil {
+			return fmt.Errorf("invalid registries: %w", err)
+		}
+		if len(registries.List) > 0 && ls.RequireDocker() {
+			// act loads the credentials of image pulls from the docker config,
+			// so the runner points it to a copy which has the credentials of the registries as well
+			dir, err := os.MkdirTemp("", "act_runner-docker-config-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			if err := registries.WriteDockerConfig(dir); err != nil {
+				return fmt.Errorf("failed to write docker config: %w", err)
+			}
+		}
+
+		if cfg.Container.Prepull.Enabled && cfg.Runner.Offline {
+			log.Warn("the runner is offline, images won't be pulled in advance")
+		} else if cfg.Container.Prepull.Enabled && ls.RequireDocker() {
+			images := ls.Images()
+			if cfg.Container.Prepull.BeforeDeclare {
+				prepullImages(ctx, images, registries)
+			} else {
+				go prepullImages(ctx, images, registries)
+			}
+			if cfg.Container.Prepull.Interval > 0 {
+				go runPrepullLoop(ctx, images, registries, cfg.Container.Prepull.Interval)
+			}
+		}
+
+		imageGC := cfg.GC.Images.Enabled && ls.RequireDocker()
+		var tracker *imagegc.Tracker
+		if imageGC {
+			tracker = imagegc.NewTracker(cfg.GC.Images.UsageFile)
+		}
+		runner := run.NewRunner(cfg, reg, cli, registries, tracker)
+
+		if *cfg.Container.OrphanCleanup.Enabled && ls.RequireDocker() {
+			cleanupOrphans(ctx, runner, *cfg.Container.OrphanCleanup.OwnOnly)
+			if cfg.Container.OrphanCleanup.Interval > 0 {
+				go runOrphanCleanupLoop(ctx, runner, cfg.Container.OrphanCleanup)
+			}
+		}
+
+		go runOutboxLoop(ctx, runner, cfg.Runner.Outbox.Interval)
 
 		// declare the labels of the runner before fetching tasks
 		resp, err := runner.Declare(ctx, ls.Names())
@@ -120,7 +191,13 @@ func runDaemon(ctx context.Context, daemArgs *daemonArgs, configFile *string) fu
 				resp.Msg.Runner.Name, resp.Msg.Runner.Version, resp.Msg.Runner.Labels)
 		}
 
-		poller := poll.New(cfg, cli, runner)
+		if imageGC {
+			go runGCLoop(ctx, cfg, runner, keptImages(cfg, ls, registries), tracker)
+		} else if cfg.GC.Enabled {
+			go runGCLoop(ctx, cfg, runner, nil, nil)
+		}
+
+		poller := poll.New(cfg, cli, runner, gate)
 
 		if daemArgs.Once || reg.Ephemeral {
 			done := make(chan struct{})
@@ -206,6 +283,45 @@ var commonSocketPaths = []string{
 	"$HOME/.docker/run/docker.sock",
 }
 
+// findDockerSockets returns the docker hosts of the sockets at commonSocketPaths which exist, in order.
+func findDockerSockets() []string {
+	var sockets []string
+	for _, p := range commonSocketPaths {
+		if _, err := os.Lstat(os.ExpandEnv(p)); err == nil {
+			if strings.HasPrefix(p, `\\.\`) {
+				sockets = append(sockets, "npipe://"+filepath.ToSlash(os.ExpandEnv(p)))
+			} else {
+				sockets = append(sockets, "unix://"+filepath.ToSlash(os.ExpandEnv(p)))
+			}
+		}
+	}
+	return sockets
+}
+
+// waitForDocker finds the docker host and waits until the docker daemon is reachable, for at most timeout.
+// The docker daemon is checked only once if timeout is 0.
+func waitForDocker(ctx context.Context, configDockerHost string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		dockerSocketPath, err := getDockerSocketPath(configDockerHost)
+		if err == nil {
+			err = envcheck.CheckIfDockerRunning(ctx, dockerSocketPath)
+		}
+		if err == nil {
+			return dockerSocketPath, nil
+		}
+		if !time.Now().Before(deadline) {
+			return "", err
+		}
+		log.WithError(err).Warn("the docker daemon isn't reachable, waiting for it")
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
 func getDockerSocketPath(configDockerHost string) (string, error) {
 	// a `-` means don't mount the docker socket to job containers
 	if configDockerHost != "" && configDockerHost != "-" {
@@ -217,13 +333,8 @@ func getDockerSocketPath(configDockerHost string) (string, error) {
 		return socket, nil
 	}
 
-	for _, p := range commonSocketPaths {
-		if _, err := os.Lstat(os.ExpandEnv(p)); err == nil {
-			if strings.HasPrefix(p, `\\.\`) {
-				return "npipe://" + filepath.ToSlash(os.ExpandEnv(p)), nil
-			}
-			return "unix://" + filepath.ToSlash(os.ExpandEnv(p)), nil
-		}
+	if sockets := findDockerSockets(); len(sockets) > 0 {
+		return sockets[0], nil
 	}
 
 	return "", fmt.Errorf("daemon Docker Engine socket not found and docker_host config was invalid")
@@ -0,0 +1,150 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	pingv1 "code.gitea.io/actions-proto-go/ping/v1"
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+
+	"gitea.com/gitea/act_runner/internal/pkg/client"
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+	"gitea.com/gitea/act_runner/internal/pkg/envcheck"
+	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
+	"gitea.com/gitea/act_runner/internal/pkg/ver"
+)
+
+type doctorArgs struct {
+	JSON bool
+}
+
+func runDoctor(configFile *string, args *doctorArgs) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		report := diagnose(cmd.Context(), *configFile)
+
+		if args.JSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			report.Print(os.Stdout)
+		}
+		if n := report.Count(envcheck.StatusFail); n > 0 {
+			return fmt.Errorf("%d checks failed", n)
+		}
+		return nil
+	}
+}
+
+// diagnose checks everything the runner needs, the checks which depend on a failed one are skipped.
+func diagnose(ctx context.Context, configFile string) *envcheck.Report {
+	report := &envcheck.Report{}
+
+	cfg, err := config.LoadDefault(configFile)
+	if err != nil {
+		report.Add(envcheck.Fail("config", "invalid configuration: %v", err))
+		return report
+	}
+	if configFile == "" {
+		report.Add(envcheck.Pass("config", "no config file, using the default configuration"))
+	} else {
+		report.Add(envcheck.Pass("config", "loaded %s", configFile))
+	}
+	if _, err := registry.FromConfig(&cfg.Container); err != nil {
+		report.Add(envcheck.Fail("config registries", "invalid registries: %v", err))
+	}
+
+	report.Add(envcheck.CheckSecretFile("registration", cfg.Runner.File))
+	reg, err := config.LoadRegistration(cfg.Runner.File)
+	if err != nil && !os.IsNotExist(err) {
+		report.Add(envcheck.Fail("registration", "failed to load registration file: %v", err))
+	}
+
+	lbls := cfg.Runner.Labels
+	if len(lbls) == 0 && reg != nil {
+		lbls = reg.Labels
+	}
+	ls := labels.Labels{}
+	for _, l := range lbls {
+		label, err := labels.Parse(l)
+		if err != nil {
+			report.Add(envcheck.Fail("labels", "invalid label %q: %v", l, err))
+			continue
+		}
+		ls = append(ls, label)
+	}
+	if len(ls) == 0 {
+		report.Add(envcheck.Warn("labels", "no labels configured, the runner may not be able to pick up jobs"))
+	} else {
+		report.Add(envcheck.Pass("labels", "%v", ls.Names()))
+	}
+
+	if reg != nil {
+		report.Add(checkGitea(ctx, cfg, reg)...)
+	}
+
+	if ls.RequireDocker() {
+		for _, socket := range findDockerSockets() {
+			report.Add(envcheck.CheckDockerSocket(ctx, socket))
+		}
+		if dockerHost, err := getDockerSocketPath(cfg.Container.DockerHost); err != nil {
+			report.Add(envcheck.Fail("docker host", "%v", err))
+		} else {
+			report.Add(envcheck.CheckDocker(ctx, dockerHost, ls.Images())...)
+		}
+	}
+
+	dirs := []string{cfg.Host.WorkdirParent}
+	if *cfg.Cache.Enabled && cfg.Cache.ExternalServer == "" {
+		report.Add(envcheck.CheckCacheServer(cfg.Cache.Host, cfg.Cache.Port)...)
+		dirs = append(dirs, cfg.Cache.Dir)
+	}
+	if cfg.Runner.Offline {
+		dirs = append(dirs, cfg.Runner.ActionStore)
+	}
+	for _, dir := range dirs {
+		report.Add(envcheck.CheckDiskSpace("disk space", dir))
+	}
+
+	return report
+}
+
+// checkGitea pings the Gitea instance, and compares the local clock with the time of its response.
+func checkGitea(ctx context.Context, cfg *config.Config, reg *config.Registration) []envcheck.Result {
+	cli := client.New(
+		reg.Address,
+		cfg.Runner.Insecure,
+		reg.UUID,
+		reg.Token,
+		ver.Version(),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	resp, err := cli.Ping(ctx, connect.NewRequest(&pingv1.PingRequest{
+		Data: reg.Name,
+	}))
+	if err != nil {
+		return []envcheck.Result{envcheck.Fail("gitea", "cannot ping %s: %v", reg.Address, err)}
+	}
+	results := []envcheck.Result{envcheck.Pass("gitea", "pinged %s in %v", reg.Address, time.Since(start).Round(time.Millisecond))}
+
+	if date, err := http.ParseTime(resp.Header().Get("Date")); err != nil {
+		results = append(results, envcheck.Warn("clock skew", "the Gitea instance didn't respond with its time"))
+	} else {
+		results = append(results, envcheck.CheckClockSkew(time.Now(), date))
+	}
+	return results
+}
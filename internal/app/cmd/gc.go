@@ -0,0 +1,240 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"gitea.com/gitea/act_runner/internal/app/run"
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/gc"
+	"gitea.com/gitea/act_runner/internal/pkg/imagegc"
+	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/lockfile"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
+)
+
+type gcArgs struct {
+	DryRun bool
+}
+
+type gcTarget struct {
+	dir    string
+	depth  int
+	policy config.GCPolicy
+	lock   func(path string) (func(), bool)
+}
+
+// gcTargets returns the directories to collect garbage in.
+// The entries in use by runner are kept, runner is nil if the runner isn't running in this process,
+// then the daemon must not be running either, see lockOutDaemon.
+func gcTargets(cfg *config.Config, runner *run.Runner) []gcTarget {
+	workdir := gcTarget{dir: cfg.Host.WorkdirParent, depth: 1, policy: cfg.GC.Workdir}
+	if runner != nil {
+		workdir.lock = runner.LockWorkdirEntry
+	}
+	targets := []gcTarget{
+		// every action clone and every task directory of host jobs is directly under the host workdir parent
+		workdir,
+	}
+	if *cfg.Cache.Enabled && cfg.Cache.ExternalServer == "" {
+		// the cache server stores every entry as a file in "cache/<bucket>/<id>",
+		// and the server treats an entry whose file is missing as a cache miss
+		targets = append(targets, gcTarget{dir: filepath.Join(cfg.Cache.Dir, "cache"), depth: 2, policy: cfg.GC.Cache})
+	}
+	if cfg.Log.Archive.Dir != "" {
+		// the logs of a run are in "<owner>/<repo>/<run>"
+		targets = append(targets, gcTarget{dir: cfg.Log.Archive.Dir, depth: 3, policy: cfg.GC.Logs})
+	}
+	return targets
+}
+
+// daemonLockFile returns the file which the daemon holds a shared lock on while it's running.
+func daemonLockFile(cfg *config.Config) string {
+	return cfg.Runner.File + ".lock"
+}
+
+// lockOutDaemon locks the daemon out until the lock is released, it fails if the daemon is running.
+// The gc commands can't tell what the tasks of the daemon use, the daemon collects garbage itself.
+func lockOutDaemon(cfg *config.Config) (*lockfile.File, error) {
+	lock, err := lockfile.TryExclusive(daemonLockFile(cfg))
+	if errors.Is(err, lockfile.ErrLocked) {
+		return nil, errors.New("the runner daemon is running, it collects garbage itself according to gc.interval, stop it or use --dry-run")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", daemonLockFile(cfg), err)
+	}
+	return lock, nil
+}
+
+func collectGarbage(cfg *config.Config, runner *run.Runner, dryRun bool) []*gc.Result {
+	var results []*gc.Result
+	for _, t := range gcTargets(cfg, runner) {
+		policy := gc.Policy{
+			MaxSize: int64(t.policy.MaxSize),
+			MaxAge:  t.policy.MaxAge,
+			Protect: t.policy.Protect,
+			Lock:    t.lock,
+		}
+		result, err := gc.Collect(t.dir, t.depth, policy, dryRun)
+		if err != nil {
+			log.WithError(err).Errorf("failed to collect garbage in %s", t.dir)
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// keptImages returns the images which the image gc never removes: the ones of the labels and the fallback image,
+// both as configured and as pulled from the mirrors of their registries.
+func keptImages(cfg *config.Config, ls labels.Labels, registries registry.Registries) []string {
+	images := ls.Images()
+	if cfg.Runner.FallbackImage != "" {
+		images = append(images, cfg.Runner.FallbackImage)
+	}
+	for _, image := range images {
+		if rewritten := registries.Rewrite(image); rewritten != image {
+			images = append(images, rewritten)
+		}
+	}
+	return images
+}
+
+func collectImages(ctx context.Context, cfg *config.Config, keep []string, tracker *imagegc.Tracker, dryRun bool) (*imagegc.Result, error) {
+	cli, err := docker.NewClient("")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	return imagegc.Collect(ctx, cli, tracker, imagegc.Policy{
+		MaxAge:  cfg.GC.Images.MaxAge,
+		MaxSize: int64(cfg.GC.Images.MaxSize),
+		Keep:    keep,
+		All:     cfg.GC.Images.All,
+	}, dryRun)
+}
+
+// runGCLoop collects garbage every interval until ctx is done.
+// The directories are collected if gc is enabled, without the entries in use by runner, and the images are collected if tracker isn't nil.
+func runGCLoop(ctx context.Context, cfg *config.Config, runner *run.Runner, keepImages []string, tracker *imagegc.Tracker) {
+	ticker := time.NewTicker(cfg.GC.Interval)
+	defer ticker.Stop()
+	for {
+		if cfg.GC.Enabled {
+			for _, result := range collectGarbage(cfg, runner, false) {
+				log.Infof("gc: freed %s by removing %d entries from %s, %d entries (%s) kept",
+					units.HumanSize(float64(result.Freed)), len(result.Removed), result.Dir, result.Kept, units.HumanSize(float64(result.Size)))
+				for _, e := range result.Removed {
+					log.Debugf("gc: removed %s (%s, last used at %s)", e.Path, units.HumanSize(float64(e.Size)), e.LastUsed.Format(time.RFC3339))
+				}
+			}
+		}
+		if tracker != nil {
+			result, err := collectImages(ctx, cfg, keepImages, tracker, false)
+			if err != nil {
+				log.WithError(err).Error("failed to collect images")
+			}
+			if result != nil {
+				log.Infof("gc: freed %s by removing %d images, %d images (%s) kept",
+					units.HumanSize(float64(result.Freed)), len(result.Removed), result.Kept, units.HumanSize(float64(result.Size)))
+				for _, img := range result.Removed {
+					log.Infof("gc: removed image %s (%s, %s)", img.Name, units.HumanSize(float64(img.Size)), img.Reason)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runGCImages(configFile *string, args *gcArgs) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		cfg, err := config.LoadDefault(*configFile)
+		if err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		initLogging(cfg)
+
+		ls := labels.Labels{}
+		if reg, err := config.LoadRegistration(cfg.Runner.File); err == nil {
+			for _, v := range reg.Labels {
+				if l, err := labels.Parse(v); err == nil {
+					ls = append(ls, l)
+				}
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load registration file: %w", err)
+		}
+		registries, err := registry.FromConfig(&cfg.Container)
+		if err != nil {
+			return fmt.Errorf("invalid registries: %w", err)
+		}
+
+		verb := "removed"
+		if args.DryRun {
+			verb = "would remove"
+		} else {
+			// the images held by the tasks of the daemon are only known to the daemon
+			lock, err := lockOutDaemon(cfg)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+		}
+		tracker := imagegc.NewTracker(cfg.GC.Images.UsageFile)
+		result, err := collectImages(cmd.Context(), cfg, keptImages(cfg, ls, registries), tracker, args.DryRun)
+		if result != nil {
+			for _, img := range result.Removed {
+				fmt.Printf("%s image %s (%s, %s)\n", verb, img.Name, units.HumanSize(float64(img.Size)), img.Reason)
+			}
+			fmt.Printf("%s %d images (%s), kept %d images (%s)\n",
+				verb, len(result.Removed), units.HumanSize(float64(result.Freed)), result.Kept, units.HumanSize(float64(result.Size)))
+		}
+		return err
+	}
+}
+
+func runGC(configFile *string, args *gcArgs) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		cfg, err := config.LoadDefault(*configFile)
+		if err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		initLogging(cfg)
+
+		verb := "removed"
+		if args.DryRun {
+			verb = "would remove"
+		} else {
+			lock, err := lockOutDaemon(cfg)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+		}
+		for _, result := range collectGarbage(cfg, nil, args.DryRun) {
+			for _, e := range result.Removed {
+				fmt.Printf("%s %s (%s, last used at %s)\n", verb, e.Path, units.HumanSize(float64(e.Size)), e.LastUsed.Format(time.RFC3339))
+			}
+			fmt.Printf("%s: %s %d entries (%s), kept %d entries (%s)\n",
+				result.Dir, verb, len(result.Removed), units.HumanSize(float64(result.Freed)), result.Kept, units.HumanSize(float64(result.Size)))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"gitea.com/gitea/act_runner/internal/pkg/actionstore"
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+type importActionsArgs struct {
+	Action string
+}
+
+func runImportActions(configFile *string, args *importActionsArgs) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, files []string) error {
+		cfg, err := config.LoadDefault(*configFile)
+		if err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		if args.Action != "" && len(files) != 1 {
+			return fmt.Errorf("--action requires exactly one archive")
+		}
+
+		store := actionstore.Store{Dir: cfg.Runner.ActionStore}
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			imported, err := store.Import(f, args.Action)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", file, err)
+			}
+			for _, action := range imported {
+				fmt.Printf("imported %s from %s\n", action, file)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+	"github.com/spf13/cobra"
+
+	"gitea.com/gitea/act_runner/internal/pkg/actionmirror"
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+type mirrorSyncArgs struct {
+	Workflows  []string
+	DefaultURL string
+}
+
+// workflowActions returns the "uses" of the remote actions and reusable workflows in the workflow files of dir.
+func workflowActions(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	var uses []string
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		workflow, err := model.ReadWorkflow(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read workflow %s: %w", file, err)
+		}
+		for _, job := range workflow.Jobs {
+			if job == nil {
+				continue
+			}
+			if job.Uses != "" && !strings.HasPrefix(job.Uses, "./") {
+				uses = append(uses, job.Uses)
+			}
+			for _, step := range job.Steps {
+				if step != nil && step.Type() == model.StepTypeUsesActionRemote {
+					uses = append(uses, step.Uses)
+				}
+			}
+		}
+	}
+	return uses, nil
+}
+
+func runMirrorSync(configFile *string, args *mirrorSyncArgs) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, actions []string) error {
+		cfg, err := config.LoadDefault(*configFile)
+		if err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		initLogging(cfg)
+
+		uses := append([]string{}, actions...)
+		for _, dir := range args.Workflows {
+			found, err := workflowActions(dir)
+			if err != nil {
+				return err
+			}
+			uses = append(uses, found...)
+		}
+		if len(uses) == 0 {
+			return fmt.Errorf("no actions to sync, pass actions or workflow directories")
+		}
+
+		cache := &actionmirror.Cache{
+			Parent:     runner.GoGitActionCache{Path: cfg.Host.WorkdirParent},
+			Rules:      actionmirror.FromConfig(cfg.Runner.ActionRewrites),
+			DefaultURL: args.DefaultURL,
+		}
+		synced := map[string]bool{}
+		var failed int
+		for _, u := range uses {
+			action, err := actionmirror.ParseAction(u, args.DefaultURL)
+			if err != nil {
+				return err
+			}
+			if synced[action.String()] {
+				continue
+			}
+			synced[action.String()] = true
+
+			sha, err := cache.Fetch(cmd.Context(), action.CacheDir, action.URL, action.Ref, "")
+			if err != nil {
+				failed++
+				fmt.Printf("failed to sync %s: %v\n", action, err)
+				continue
+			}
+			fmt.Printf("synced %s at %s\n", action, sha)
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to sync %d of %d actions", failed, len(synced))
+		}
+		return nil
+	}
+}
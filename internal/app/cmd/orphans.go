@@ -0,0 +1,39 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/app/run"
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+// cleanupOrphans removes the resources left behind by tasks and logs what has been removed.
+func cleanupOrphans(ctx context.Context, runner *run.Runner, ownOnly bool) {
+	removed, err := runner.CleanupOrphans(ctx, ownOnly)
+	for _, r := range removed {
+		log.Infof("removed orphaned %s %s of task %d", r.Kind, r.Name, r.TaskID)
+	}
+	if err != nil {
+		log.WithError(err).Error("failed to remove orphaned containers, networks and volumes")
+	}
+}
+
+// runOrphanCleanupLoop removes the resources left behind by tasks every interval until ctx is done.
+func runOrphanCleanupLoop(ctx context.Context, runner *run.Runner, cfg config.OrphanCleanup) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cleanupOrphans(ctx, runner, *cfg.OwnOnly)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/app/run"
+)
+
+// runOutboxLoop reports what's in the outboxes of tasks every interval until ctx is done.
+// The outboxes left by the previous run of the runner are reported at first.
+func runOutboxLoop(ctx context.Context, runner *run.Runner, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := runner.DeliverOutboxes(ctx); err != nil {
+			log.WithError(err).Warn("failed to report the outboxes of tasks, will retry later")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
+)
+
+// prepullImages pulls the images one by one, from the mirrors of their registries and with their credentials.
+// Failures are logged and don't stop the others.
+func prepullImages(ctx context.Context, images []string, registries registry.Registries) {
+	logger := log.WithField("module", "prepull")
+	cli, err := docker.NewClient("")
+	if err != nil {
+		logger.WithError(err).Error("cannot connect to the docker daemon")
+		return
+	}
+	defer cli.Close()
+
+	start := time.Now()
+	for i, image := range images {
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Infof("warming up image %d/%d", i+1, len(images))
+		image = registries.Rewrite(image)
+		auth, err := registries.EncodedAuth(image)
+		if err != nil {
+			logger.WithError(err).Errorf("failed to encode the credentials of image %s", image)
+			continue
+		}
+		if err := docker.PullImage(ctx, cli, image, types.ImagePullOptions{RegistryAuth: auth}, logger); err != nil {
+			logger.WithError(err).Errorf("failed to pull image %s", image)
+		}
+	}
+	logger.Infof("warm-up of %d images finished in %s", len(images), time.Since(start).Round(time.Second))
+}
+
+// runPrepullLoop pulls the images every interval until ctx is done.
+func runPrepullLoop(ctx context.Context, images []string, registries registry.Registries, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			prepullImages(ctx, images, registries)
+		}
+	}
+}
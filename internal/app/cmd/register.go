@@ -331,11 +331,12 @@ func doRegister(ctx context.Context, cfg *config.Config, inputs *registerInputs)
 		Ephemeral: inputs.Ephemeral,
 	}
 
-	ls := make([]string, len(reg.Labels))
-	for i, v := range reg.Labels {
+	parsed := make(labels.Labels, 0, len(reg.Labels))
+	for _, v := range reg.Labels {
 		l, _ := labels.Parse(v)
-		ls[i] = l.Name
+		parsed = append(parsed, l)
 	}
+	ls := parsed.Names()
 	// register new runner.
 	resp, err := cli.Register(ctx, connect.NewRequest(&runnerv1.RegisterRequest{
 		Name:        reg.Name,
@@ -20,10 +20,17 @@ import (
 	"gitea.com/gitea/act_runner/internal/pkg/config"
 )
 
+// Gate decides whether tasks can be fetched.
+type Gate interface {
+	// Wait blocks until tasks can be fetched or ctx is done.
+	Wait(ctx context.Context) error
+}
+
 type Poller struct {
 	client       client.Client
 	runner       *run.Runner
 	cfg          *config.Config
+	gate         Gate         // gate is waited for before fetching a task, nil means tasks can always be fetched.
 	tasksVersion atomic.Int64 // tasksVersion used to store the version of the last task fetched from the Gitea.
 
 	pollingCtx      context.Context
@@ -35,7 +42,7 @@ type Poller struct {
 	done chan struct{}
 }
 
-func New(cfg *config.Config, client client.Client, runner *run.Runner) *Poller {
+func New(cfg *config.Config, client client.Client, runner *run.Runner, gate Gate) *Poller {
 	pollingCtx, shutdownPolling := context.WithCancel(context.Background())
 
 	jobsCtx, shutdownJobs := context.WithCancel(context.Background())
@@ -46,6 +53,7 @@ func New(cfg *config.Config, client client.Client, runner *run.Runner) *Poller {
 		client: client,
 		runner: runner,
 		cfg:    cfg,
+		gate:   gate,
 
 		pollingCtx:      pollingCtx,
 		shutdownPolling: shutdownPolling,
@@ -130,6 +138,11 @@ func (p *Poller) pollOnce(limiter *rate.Limiter) {
 			}
 			return
 		}
+		if p.gate != nil {
+			if err := p.gate.Wait(p.pollingCtx); err != nil {
+				return
+			}
+		}
 		task, ok := p.fetchTask(p.pollingCtx)
 		if !ok {
 			continue
@@ -0,0 +1,313 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/runner"
+	"gopkg.in/yaml.v3"
+
+	"gitea.com/gitea/act_runner/internal/pkg/actionstore"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
+)
+
+// actionLocks holds a mutex for every clone of an action, keyed by the name of its directory in the action cache.
+var actionLocks sync.Map
+
+// cloneNameReplacer replaces the characters of the cache dir of an action like runner.GoGitActionCache does.
+var cloneNameReplacer = strings.NewReplacer(`<`, "-", `>`, "-", `:`, "-", `"`, "-", `/`, "-", `\`, "-", `|`, "-", `?`, "-", `*`, "-")
+
+// lockAction locks the clone of an action with the given directory name, like "owner-repo.git".
+func lockAction(name string) (unlock func()) {
+	v, _ := actionLocks.LoadOrStore(name, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// insecureSkipTLSKey marks the context of the fetches which skip the verification of TLS certificates.
+type insecureSkipTLSKey struct{}
+
+// installGitTransport replaces the HTTPS transport of go-git, since runner.GoGitActionCache has no option to skip
+// the verification of TLS certificates, unlike the clones of act. Only the fetches marked with insecureSkipTLSKey skip it.
+var installGitTransport = sync.OnceFunc(func() {
+	insecure := http.DefaultTransport.(*http.Transport).Clone()
+	insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	client.InstallProtocol("https", githttp.NewClient(&http.Client{Transport: gitTransport{insecure: insecure}}))
+})
+
+type gitTransport struct {
+	insecure http.RoundTripper
+}
+
+func (t gitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if skip, _ := req.Context().Value(insecureSkipTLSKey{}).(bool); skip {
+		return t.insecure.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// actionUses counts the tasks using every clone of an action, keyed like actionLocks.
+// A clone is used from its first fetch by a task until the task ends, since act reads it again after fetching it.
+var actionUses = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: map[string]int{}}
+
+// actionInUse returns whether a running task uses the clone of an action with the given directory name.
+func actionInUse(name string) bool {
+	actionUses.Lock()
+	defer actionUses.Unlock()
+	return actionUses.counts[name] > 0
+}
+
+// lockingActionCache is an ActionCache shared by concurrent tasks, every task has its own.
+// Fetches of the same action are serialized, because they write to the same bare repository.
+type lockingActionCache struct {
+	parent runner.ActionCache
+	// insecure indicates whether to skip the verification of TLS certificates, like runner.insecure does for act.
+	insecure bool
+
+	mu   sync.Mutex
+	used map[string]bool // used are the clones which the task uses, until release is called.
+}
+
+// use marks the clone of an action as used by the task.
+func (c *lockingActionCache) use(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used[name] {
+		return
+	}
+	if c.used == nil {
+		c.used = map[string]bool{}
+	}
+	c.used[name] = true
+
+	actionUses.Lock()
+	defer actionUses.Unlock()
+	actionUses.counts[name]++
+}
+
+// release marks the clones used by the task as unused, when the task ends.
+func (c *lockingActionCache) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	actionUses.Lock()
+	defer actionUses.Unlock()
+	for name := range c.used {
+		if actionUses.counts[name]--; actionUses.counts[name] <= 0 {
+			delete(actionUses.counts, name)
+		}
+	}
+	c.used = nil
+}
+
+func (c *lockingActionCache) Fetch(ctx context.Context, cacheDir, url, ref, _ string) (string, error) {
+	name := cloneNameReplacer.Replace(cacheDir) + ".git"
+	// the clone is marked before it's locked, so the gc either sees it's in use, or has removed it before it's fetched again
+	c.use(name)
+	defer lockAction(name)()
+
+	if c.insecure {
+		installGitTransport()
+		ctx = context.WithValue(ctx, insecureSkipTLSKey{}, true)
+	}
+
+	// Like act without a custom action cache, the task token isn't sent,
+	// because the token comes from the instance which triggered the task, and actions could be cloned from other instances.
+	return c.parent.Fetch(ctx, cacheDir, url, ref, "")
+}
+
+func (c *lockingActionCache) GetTarArchive(ctx context.Context, cacheDir, sha, includePrefix string) (io.ReadCloser, error) {
+	return c.parent.GetTarArchive(ctx, cacheDir, sha, includePrefix)
+}
+
+// offlineActionCache is an ActionCache which never clones actions, they are resolved from the action store only.
+type offlineActionCache struct {
+	store actionstore.Store
+
+	mu    sync.Mutex
+	local runner.LocalRepositoryCache
+}
+
+func newOfflineActionCache(store actionstore.Store) *offlineActionCache {
+	return &offlineActionCache{
+		store: store,
+		local: runner.LocalRepositoryCache{
+			Parent:            unavailableActionCache{},
+			LocalRepositories: map[string]string{},
+			CacheDirCache:     map[string]string{},
+		},
+	}
+}
+
+func (c *offlineActionCache) Fetch(ctx context.Context, cacheDir, url, ref, token string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the clone URL is like "https://gitea.com/owner/repo", the instance could have a sub path
+	parts := strings.Split(strings.TrimSuffix(url, "/"), "/")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid action URL %q", url)
+	}
+	repo := parts[len(parts)-2] + "/" + parts[len(parts)-1]
+	if !c.store.Has(repo, ref) {
+		return "", fmt.Errorf("action %s@%s is not in the action store %s, it can't be cloned in offline mode", repo, ref, c.store.Dir)
+	}
+	c.local.LocalRepositories[repo+"@"+ref] = c.store.Path(repo, ref)
+	return c.local.Fetch(ctx, cacheDir, repo, ref, token)
+}
+
+func (c *offlineActionCache) GetTarArchive(ctx context.Context, cacheDir, sha, includePrefix string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.local.GetTarArchive(ctx, cacheDir, sha, includePrefix)
+}
+
+// unavailableActionCache fails every operation, it's the parent of the local repositories in offline mode.
+type unavailableActionCache struct{}
+
+func (unavailableActionCache) Fetch(_ context.Context, _, url, ref, _ string) (string, error) {
+	return "", fmt.Errorf("action %s@%s can't be cloned in offline mode", url, ref)
+}
+
+func (unavailableActionCache) GetTarArchive(_ context.Context, cacheDir, sha, _ string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("action %s@%s is not available in offline mode", cacheDir, sha)
+}
+
+// registryActionCache is an ActionCache which rewrites the "docker://" images in the metadata files of actions,
+// like the ones of the "docker://" steps of jobs, since act pulls them as they are.
+type registryActionCache struct {
+	parent     runner.ActionCache
+	registries registry.Registries
+}
+
+func (c *registryActionCache) Fetch(ctx context.Context, cacheDir, url, ref, token string) (string, error) {
+	return c.parent.Fetch(ctx, cacheDir, url, ref, token)
+}
+
+func (c *registryActionCache) GetTarArchive(ctx context.Context, cacheDir, sha, includePrefix string) (io.ReadCloser, error) {
+	archive, err := c.parent.GetTarArchive(ctx, cacheDir, sha, includePrefix)
+	if err != nil {
+		return nil, err
+	}
+	if name := path.Base(includePrefix); name != "action.yml" && name != "action.yaml" {
+		return archive, nil
+	}
+	defer archive.Close()
+
+	content, err := rewriteActionImage(archive, func(image string) string {
+		rewritten := c.registries.Rewrite(image)
+		if rewritten != image {
+			common.Logger(ctx).Infof("image %s of action %s is pulled from %s", image, cacheDir, rewritten)
+		}
+		return rewritten
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(content), nil
+}
+
+// rewriteActionImage rewrites the "docker://" images of the metadata files of actions in a tar archive.
+// The other entries, like the link the metadata file could be, are kept as they are.
+func rewriteActionImage(r io.Reader, rewrite func(image string) string) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if rewritten, ok := rewriteRunsImage(content, rewrite); ok {
+				content = rewritten
+				hdr.Size = int64(len(content))
+			}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// rewriteRunsImage rewrites the "docker://" images of the metadata of an action,
+// which are the image of a docker action, and the images of the "docker://" steps of a composite action.
+// It's false if no image is rewritten, then the metadata is kept as it is.
+func rewriteRunsImage(content []byte, rewrite func(image string) string) ([]byte, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return nil, false
+	}
+	runs := mappingValue(doc.Content[0], "runs")
+	images := []*yaml.Node{mappingValue(runs, "image")}
+	if steps := mappingValue(runs, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+		for _, step := range steps.Content {
+			images = append(images, mappingValue(step, "uses"))
+		}
+	}
+
+	rewritten := false
+	for _, image := range images {
+		if image == nil || !strings.HasPrefix(image.Value, "docker://") {
+			continue
+		}
+		if v := "docker://" + rewrite(strings.TrimPrefix(image.Value, "docker://")); v != image.Value {
+			image.Value = v
+			rewritten = true
+		}
+	}
+	if !rewritten {
+		return nil, false
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// mappingValue returns the value of the key of a mapping node, it's nil if node isn't a mapping or has no such key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/nektos/act/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRewriteActionImage(t *testing.T) {
+	rewrite := func(image string) string {
+		return strings.Replace(image, "docker.io/", "mirror.local/", 1)
+	}
+	readArchive := func(t *testing.T, r io.Reader) string {
+		tr := tar.NewReader(r)
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), hdr.Size)
+		return string(content)
+	}
+	writeArchive := func(t *testing.T, content string) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "action.yml", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		return buf
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]interface{}
+	}{
+		{
+			name:    "docker action",
+			content: "name: app\nruns:\n  using: docker\n  image: docker://docker.io/owner/app:1\n",
+			want:    map[string]interface{}{"using": "docker", "image": "docker://mirror.local/owner/app:1"},
+		},
+		{
+			name:    "composite action",
+			content: "runs:\n  using: composite\n  steps:\n    - uses: docker://docker.io/owner/app:1\n    - run: echo\n",
+			want: map[string]interface{}{"using": "composite", "steps": []interface{}{
+				map[string]interface{}{"uses": "docker://mirror.local/owner/app:1"},
+				map[string]interface{}{"run": "echo"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := rewriteActionImage(writeArchive(t, tt.content), rewrite)
+			require.NoError(t, err)
+			var action struct {
+				Runs map[string]interface{} `yaml:"runs"`
+			}
+			require.NoError(t, yaml.Unmarshal([]byte(readArchive(t, buf)), &action))
+			assert.Equal(t, tt.want, action.Runs)
+		})
+	}
+
+	// the metadata is kept as it is if there is nothing to rewrite
+	for _, content := range []string{
+		"runs:\n  using: docker\n  image: Dockerfile # built locally\n",
+		"runs:\n  using: docker\n  image: docker://ghcr.io/owner/app:1\n",
+		"not: [valid",
+	} {
+		buf, err := rewriteActionImage(writeArchive(t, content), rewrite)
+		require.NoError(t, err)
+		assert.Equal(t, content, readArchive(t, buf))
+	}
+}
+
+func TestLockingActionCache_Fetch_insecure(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, nil)
+	}))
+	// the handshakes failing on purpose aren't logged
+	server.Config.ErrorLog = stdlog.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	fetch := func(insecure bool) error {
+		c := &lockingActionCache{
+			parent:   runner.GoGitActionCache{Path: t.TempDir()},
+			insecure: insecure,
+		}
+		defer c.release()
+		_, err := c.Fetch(context.Background(), "owner/repo", server.URL+"/owner/repo", "v1", "")
+		return err
+	}
+
+	// the certificate of the server is self-signed
+	require.Error(t, fetch(false))
+	assert.Zero(t, requests.Load())
+
+	assert.ErrorIs(t, fetch(true), transport.ErrRepositoryNotFound)
+	assert.NotZero(t, requests.Load())
+
+	// the other fetches still verify the certificates
+	requests.Store(0)
+	require.Error(t, fetch(false))
+	assert.Zero(t, requests.Load())
+}
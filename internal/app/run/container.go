@@ -0,0 +1,42 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// updateJobContainer calls fn with the container spec of the job, if it has one, and saves the changes to the job.
+// The spec returned by Job.Container is decoded from the raw node every time, so it has to be encoded back.
+func updateJobContainer(job *model.Job, fn func(spec *model.ContainerSpec)) error {
+	spec := job.Container()
+	if spec == nil {
+		return nil
+	}
+	fn(spec)
+
+	var node yaml.Node
+	if err := node.Encode(spec); err != nil {
+		return err
+	}
+	job.RawContainer = node
+	return nil
+}
+
+// updateServices calls fn with the container spec of every service of the job.
+func updateServices(job *model.Job, fn func(name string, spec *model.ContainerSpec)) {
+	for name, spec := range job.Services {
+		if spec != nil {
+			fn(name, spec)
+		}
+	}
+}
+
+// appendOptions appends container options, so they take precedence over the existing ones.
+func appendOptions(options, extra string) string {
+	return strings.TrimSpace(options + " " + extra)
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockWorkdirEntry locks an entry directly under the host workdir parent before the gc removes it, and returns the function to unlock it.
+// It returns false for the directory of a running task and the clone of an action used by a running task, which must be kept.
+// The other clones are locked against fetches, which write to them.
+func (r *Runner) LockWorkdirEntry(path string) (func(), bool) {
+	name := filepath.Base(path)
+	if id, ok := strings.CutPrefix(name, "task-"); ok {
+		if taskID, err := strconv.ParseInt(id, 10, 64); err == nil {
+			if _, running := r.runningTasks.Load(taskID); running {
+				return nil, false
+			}
+		}
+	}
+	if strings.HasSuffix(name, ".git") {
+		unlock := lockAction(name)
+		if actionInUse(name) {
+			unlock()
+			return nil, false
+		}
+		return unlock, true
+	}
+	return func() {}, true
+}
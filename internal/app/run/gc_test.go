@@ -0,0 +1,54 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_LockWorkdirEntry(t *testing.T) {
+	r := &Runner{}
+	r.runningTasks.Store(int64(42), struct{}{})
+
+	_, ok := r.LockWorkdirEntry("/workdir/task-42")
+	assert.False(t, ok)
+
+	unlock, ok := r.LockWorkdirEntry("/workdir/task-43")
+	assert.True(t, ok)
+	unlock()
+
+	// a fetch of the action waits until the clone has been removed
+	unlock, ok = r.LockWorkdirEntry("/workdir/owner-repo.git")
+	assert.True(t, ok)
+	fetched := make(chan struct{})
+	go func() {
+		defer lockAction("owner-repo.git")()
+		close(fetched)
+	}()
+	select {
+	case <-fetched:
+		t.Fatal("the clone has been fetched while it's locked")
+	default:
+	}
+	unlock()
+	<-fetched
+}
+
+func TestRunner_LockWorkdirEntry_used(t *testing.T) {
+	r := &Runner{}
+	cache := &lockingActionCache{parent: unavailableActionCache{}}
+	_, _ = cache.Fetch(context.Background(), "owner/used", "https://gitea.com/owner/used", "v1", "")
+
+	// the clone is kept after the fetch, until the task ends
+	_, ok := r.LockWorkdirEntry("/workdir/owner-used.git")
+	assert.False(t, ok)
+
+	cache.release()
+	unlock, ok := r.LockWorkdirEntry("/workdir/owner-used.git")
+	assert.True(t, ok)
+	unlock()
+}
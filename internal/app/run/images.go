@@ -0,0 +1,135 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/imagepolicy"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// enforceImagePolicy checks the images of the job container, the service containers and the docker:// steps of the job,
+// and pins them to digests. Every violation is written to the job log, and the job is refused if there is any.
+// The images of the actions running in docker, which come from their action.yml, are not checked.
+func enforceImagePolicy(job *model.Job, policy *imagepolicy.Policy, reporter *report.Reporter) error {
+	if !policy.Enabled() {
+		return nil
+	}
+
+	var violations int
+	check := func(kind, image string) string {
+		pinned, err := policy.Check(image)
+		if err != nil {
+			violations++
+			reporter.Logf("%s: %v", kind, err)
+			return image
+		}
+		if pinned != image {
+			reporter.Logf("%s: image %s is pinned to %s", kind, image, pinned)
+		}
+		return pinned
+	}
+
+	if err := updateJobContainer(job, func(spec *model.ContainerSpec) {
+		spec.Image = check("job container", spec.Image)
+	}); err != nil {
+		return err
+	}
+	updateServices(job, func(name string, spec *model.ContainerSpec) {
+		spec.Image = check("service "+name, spec.Image)
+	})
+	for _, step := range job.Steps {
+		if step == nil || step.Type() != model.StepTypeUsesDockerURL {
+			continue
+		}
+		image := strings.TrimPrefix(step.Uses, "docker://")
+		step.Uses = "docker://" + check("step "+step.String(), image)
+	}
+
+	if violations > 0 {
+		return fmt.Errorf("%d images are not allowed by the image policy of the runner", violations)
+	}
+	return nil
+}
+
+// applyRegistries rewrites the images of the job container, the service containers and the docker actions of the job
+// to the mirrors of their registries, and sets the credentials of the registries to the containers without their own.
+// The credentials are set for any image on the registry, rewritten or not, so every job can pull what they give access to.
+// Docker actions get the credentials from the docker config written by the daemon.
+func applyRegistries(job *model.Job, registries registry.Registries, reporter *report.Reporter) error {
+	if registries.Empty() {
+		return nil
+	}
+
+	rewrite := func(kind string, spec *model.ContainerSpec) {
+		if image := registries.Rewrite(spec.Image); image != spec.Image {
+			reporter.Logf("%s: image %s is pulled from %s", kind, spec.Image, image)
+			spec.Image = image
+		}
+		if len(spec.Credentials) > 0 {
+			return
+		}
+		if username, password, ok := registries.Credentials(spec.Image); ok {
+			spec.Credentials = map[string]string{
+				"username": username,
+				"password": password,
+			}
+		}
+	}
+
+	if err := updateJobContainer(job, func(spec *model.ContainerSpec) {
+		rewrite("job container", spec)
+	}); err != nil {
+		return err
+	}
+	updateServices(job, func(name string, spec *model.ContainerSpec) {
+		rewrite("service "+name, spec)
+	})
+	for _, step := range job.Steps {
+		if step == nil || step.Type() != model.StepTypeUsesDockerURL {
+			continue
+		}
+		image := strings.TrimPrefix(step.Uses, "docker://")
+		if rewritten := registries.Rewrite(image); rewritten != image {
+			reporter.Logf("step %s: image %s is pulled from %s", step.String(), image, rewritten)
+			step.Uses = "docker://" + rewritten
+		}
+	}
+	return nil
+}
+
+// holdImages holds the images of the containers created for the task, until ctx is done,
+// so the image gc doesn't remove them, including the ones built for docker actions.
+func (r *Runner) holdImages(ctx context.Context, prefix string) {
+	if r.images == nil {
+		return
+	}
+	cli, err := docker.NewClient("")
+	if err != nil {
+		log.WithError(err).Warn("cannot track the images used by the task")
+		return
+	}
+	defer cli.Close()
+
+	var releases []func()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+	err = docker.WatchCreate(ctx, cli, prefix, func(_, image string) {
+		releases = append(releases, r.images.Hold(image))
+	})
+	if err != nil {
+		log.WithError(err).Warn("stopped tracking the images used by the task")
+	}
+}
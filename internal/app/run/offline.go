@@ -0,0 +1,101 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/model"
+
+	"gitea.com/gitea/act_runner/internal/pkg/actionstore"
+	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// requiredImages returns the images the job runs in: the platform, the job container, the service containers and the docker actions.
+// The images of actions using docker are only known after the actions are resolved, so they are left out.
+func requiredImages(job *model.Job, platform string) []string {
+	var images []string
+	add := func(image string) {
+		if image != "" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+
+	if spec := job.Container(); spec != nil && spec.Image != "" {
+		add(spec.Image)
+	} else if platform != labels.PlatformHost {
+		add(platform)
+	}
+	for _, spec := range job.Services {
+		if spec != nil {
+			add(spec.Image)
+		}
+	}
+	for _, step := range job.Steps {
+		if step != nil && step.Type() == model.StepTypeUsesDockerURL {
+			add(strings.TrimPrefix(step.Uses, "docker://"))
+		}
+	}
+	return images
+}
+
+// requiredActions returns the remote actions, and the remote reusable workflow, used by the job, like "owner/repo@ref".
+// Actions used by composite actions are only known after the composite actions are resolved, so they are left out.
+func requiredActions(job *model.Job) []string {
+	var actions []string
+	add := func(uses string) {
+		if !slices.Contains(actions, uses) {
+			actions = append(actions, uses)
+		}
+	}
+
+	if job.Uses != "" && !strings.HasPrefix(job.Uses, "./") {
+		add(job.Uses)
+	}
+	for _, step := range job.Steps {
+		if step != nil && step.Type() == model.StepTypeUsesActionRemote {
+			add(step.Uses)
+		}
+	}
+	return actions
+}
+
+// checkOffline makes sure every image and action the job requires is available locally,
+// since the job would wait for network timeouts otherwise. All missing ones are written to the job log.
+func checkOffline(ctx context.Context, job *model.Job, platform string, store actionstore.Store, reporter *report.Reporter) error {
+	var missingImages, missingActions []string
+
+	for _, image := range requiredImages(job, platform) {
+		exists, err := container.ImageExistsLocally(ctx, image, "")
+		if err != nil {
+			return fmt.Errorf("check image %s: %w", image, err)
+		}
+		if !exists {
+			missingImages = append(missingImages, image)
+		}
+	}
+	for _, uses := range requiredActions(job) {
+		repo, ref, err := actionstore.ParseAction(uses)
+		if err != nil || !store.Has(repo, ref) {
+			missingActions = append(missingActions, uses)
+		}
+	}
+
+	if len(missingImages) == 0 && len(missingActions) == 0 {
+		return nil
+	}
+	reporter.Logf("the runner is offline, but the job requires images or actions which are not available locally")
+	for _, image := range missingImages {
+		reporter.Logf("missing image: %s", image)
+	}
+	for _, uses := range missingActions {
+		reporter.Logf("missing action: %s", uses)
+	}
+	return fmt.Errorf("%d images and %d actions required by the job are not available offline", len(missingImages), len(missingActions))
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/client"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+)
+
+// CleanupOrphans removes the containers, networks and volumes of tasks which are not running on the runner,
+// they are left behind when the runner or the host stops in the middle of a job.
+// If ownOnly is true, only the ones created by the runner, which are labeled with its UUID, are removed.
+// Otherwise, the ones without the label, created by older versions of the runner, are removed too.
+// The ones labeled with the UUID of another runner sharing the docker daemon are never removed,
+// the runner can't tell whether their tasks are running.
+func (r *Runner) CleanupOrphans(ctx context.Context, ownOnly bool) ([]docker.Resource, error) {
+	cli, err := docker.NewClient("")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	var labels map[string]string
+	if ownOnly {
+		labels = map[string]string{docker.LabelRunnerUUID: r.uuid}
+	}
+	resources, err := docker.ListTaskResources(ctx, cli, containerNamePrefix, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		removed []docker.Resource
+		errs    []error
+	)
+	for _, res := range resources {
+		if !r.isOrphan(res) {
+			continue
+		}
+		if err := docker.RemoveResource(ctx, cli, res); client.IsErrNotFound(err) {
+			// it has been removed since it was listed
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("remove %s %s: %w", res.Kind, res.Name, err))
+			continue
+		}
+		removed = append(removed, res)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// isOrphan returns whether a resource isn't used by a running task of the runner, nor created by another runner.
+func (r *Runner) isOrphan(res docker.Resource) bool {
+	if uuid, ok := res.Labels[docker.LabelRunnerUUID]; ok && uuid != r.uuid {
+		return false
+	}
+	_, running := r.runningTasks.Load(res.TaskID)
+	return !running
+}
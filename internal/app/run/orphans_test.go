@@ -0,0 +1,34 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+)
+
+func TestRunner_isOrphan(t *testing.T) {
+	r := &Runner{uuid: "own"}
+	r.runningTasks.Store(int64(1), struct{}{})
+
+	tests := []struct {
+		name string
+		res  docker.Resource
+		want bool
+	}{
+		{"own", docker.Resource{TaskID: 2, Labels: map[string]string{docker.LabelRunnerUUID: "own"}}, true},
+		{"own running", docker.Resource{TaskID: 1, Labels: map[string]string{docker.LabelRunnerUUID: "own"}}, false},
+		{"unlabeled", docker.Resource{TaskID: 2}, true},
+		{"unlabeled running", docker.Resource{TaskID: 1}, false},
+		{"other runner", docker.Resource{TaskID: 2, Labels: map[string]string{docker.LabelRunnerUUID: "other"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.isOrphan(tt.res))
+		})
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// DeliverOutboxes reports the logs and final states which tasks couldn't report to the Gitea instance,
+// the tasks which are running on the runner are skipped.
+func (r *Runner) DeliverOutboxes(ctx context.Context) error {
+	return report.DeliverOutboxes(ctx, r.client, r.cfg.Runner.Outbox.Dir, func(taskID int64) bool {
+		_, ok := r.runningTasks.Load(taskID)
+		return ok
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// resolveResources returns the resource limits of a job of repo running with the given runs-on labels.
+// The matching overrides are applied in order over the defaults, fields which are not set in an override are kept.
+func resolveResources(cfg *config.Container, repo string, runsOn []string, label *labels.Label) config.Resources {
+	res := cfg.Resources
+	for _, o := range cfg.ResourceOverrides {
+		if !matchOverride(o, repo, runsOn, label) {
+			continue
+		}
+		if o.CPUs > 0 {
+			res.CPUs = o.CPUs
+		}
+		if o.Memory > 0 {
+			res.Memory = o.Memory
+		}
+		if o.MemorySwap != 0 {
+			res.MemorySwap = o.MemorySwap
+		}
+		if o.PidsLimit != 0 {
+			res.PidsLimit = o.PidsLimit
+		}
+		if o.Disk > 0 {
+			res.Disk = o.Disk
+		}
+	}
+	return res
+}
+
+func matchOverride(o config.ResourceOverride, repo string, runsOn []string, label *labels.Label) bool {
+	for _, l := range o.Labels {
+		if slices.Contains(runsOn, l) || (label != nil && label.Name == l) {
+			return true
+		}
+	}
+	return len(o.Repos) > 0 && matchAllowedRepo(repo, o.Repos)
+}
+
+// resourceOptions returns the resource limits as container options.
+func resourceOptions(res config.Resources) string {
+	var opts []string
+	if res.CPUs > 0 {
+		opts = append(opts, "--cpus="+strconv.FormatFloat(res.CPUs, 'f', -1, 64))
+	}
+	if res.Memory > 0 {
+		opts = append(opts, fmt.Sprintf("--memory=%d", res.Memory))
+	}
+	if res.MemorySwap != 0 {
+		opts = append(opts, fmt.Sprintf("--memory-swap=%d", res.MemorySwap))
+	}
+	if res.PidsLimit != 0 {
+		opts = append(opts, fmt.Sprintf("--pids-limit=%d", res.PidsLimit))
+	}
+	if res.Disk > 0 {
+		opts = append(opts, fmt.Sprintf("--storage-opt=size=%d", res.Disk))
+	}
+	return strings.Join(opts, " ")
+}
+
+// applyResources adds the resource limits to the options of the job container and the service containers.
+// They are appended to the options of the workflow too, so they can't be raised by the workflow.
+func applyResources(cfg *runner.Config, job *model.Job, res config.Resources) error {
+	opts := resourceOptions(res)
+	if opts == "" {
+		return nil
+	}
+	cfg.ContainerOptions = appendOptions(cfg.ContainerOptions, opts)
+	if err := updateJobContainer(job, func(spec *model.ContainerSpec) {
+		spec.Options = appendOptions(spec.Options, opts)
+	}); err != nil {
+		return err
+	}
+	updateServices(job, func(_ string, spec *model.ContainerSpec) {
+		spec.Options = appendOptions(spec.Options, opts)
+	})
+	return nil
+}
+
+// watchOOM reports the containers of the task killed because of running out of memory to the job log,
+// since the steps running in them just fail with a cryptic exit code.
+func (r *Runner) watchOOM(ctx context.Context, prefix string, res config.Resources, reporter *report.Reporter) {
+	// act creates the containers on the docker host of DOCKER_HOST, which the daemon sets to the one of the runner,
+	// the docker_host of a label only changes the one mounted to the job container
+	cli, err := docker.NewClient("")
+	if err != nil {
+		log.WithError(err).Warn("cannot watch out-of-memory kills of job containers")
+		return
+	}
+	defer cli.Close()
+
+	limit := "no limit configured by the runner"
+	if res.Memory > 0 {
+		limit = "the memory limit is " + res.Memory.String()
+	}
+	err = docker.WatchOOM(ctx, cli, prefix, func(name string) {
+		log.Warnf("container %s has been killed because it ran out of memory", name)
+		reporter.Logf("container %s has been killed because it ran out of memory (%s)", name, limit)
+	})
+	if err != nil {
+		log.WithError(err).Warn("stopped watching out-of-memory kills of job containers")
+	}
+}
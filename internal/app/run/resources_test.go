@@ -0,0 +1,82 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+func TestResolveResources(t *testing.T) {
+	cfg := &config.Container{
+		Resources: config.Resources{CPUs: 2, Memory: 4 << 30},
+		ResourceOverrides: []config.ResourceOverride{
+			{Labels: []string{"large"}, Resources: config.Resources{CPUs: 8, Memory: 32 << 30}},
+			{Repos: []string{"org1/*"}, Resources: config.Resources{PidsLimit: 100, MemorySwap: -1}},
+		},
+	}
+
+	res := resolveResources(cfg, "org2/repo", []string{"ubuntu"}, nil)
+	assert.Equal(t, "--cpus=2 --memory=4294967296", resourceOptions(res))
+
+	res = resolveResources(cfg, "org2/repo", []string{"ubuntu", "large"}, nil)
+	assert.Equal(t, "--cpus=8 --memory=34359738368", resourceOptions(res))
+
+	res = resolveResources(cfg, "org1/repo", []string{"large"}, nil)
+	assert.Equal(t, "--cpus=8 --memory=34359738368 --memory-swap=-1 --pids-limit=100", resourceOptions(res))
+}
+
+func TestApplyResources(t *testing.T) {
+	workflow, err := model.ReadWorkflow(strings.NewReader(`
+name: test
+on: push
+jobs:
+  job1:
+    runs-on: ubuntu
+    container:
+      image: node:18
+      options: --cpus=16
+    services:
+      db:
+        image: postgres
+    steps:
+      - run: echo
+`))
+	require.NoError(t, err)
+	job := workflow.GetJob("job1")
+
+	cfg := &runner.Config{}
+	require.NoError(t, applyResources(cfg, job, config.Resources{CPUs: 2}))
+	assert.Equal(t, "--cpus=2", cfg.ContainerOptions)
+	assert.Equal(t, "node:18", job.Container().Image)
+	assert.Equal(t, "--cpus=16 --cpus=2", job.Container().Options)
+	assert.Equal(t, "--cpus=2", job.Services["db"].Options)
+}
+
+func TestAddStepContainerOptions(t *testing.T) {
+	unregister := registerStepContainerOptions("GITEA-ACTIONS-TASK-12", "--cpus=2")
+	defer unregister()
+
+	input := &container.NewContainerInput{Name: "GITEA-ACTIONS-TASK-12-WORKFLOW-test-JOB-job1_STEP-build"}
+	addStepContainerOptions(input)
+	assert.Equal(t, "--cpus=2", input.Options)
+
+	// the containers of other tasks are left alone
+	input = &container.NewContainerInput{Name: "GITEA-ACTIONS-TASK-123-WORKFLOW-test-JOB-job1_STEP-build"}
+	addStepContainerOptions(input)
+	assert.Empty(t, input.Options)
+
+	unregister()
+	input = &container.NewContainerInput{Name: "GITEA-ACTIONS-TASK-12-WORKFLOW-test-JOB-job1_STEP-build"}
+	addStepContainerOptions(input)
+	assert.Empty(t, input.Options)
+}
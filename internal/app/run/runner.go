@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,9 +24,17 @@ import (
 	"github.com/nektos/act/pkg/runner"
 	log "github.com/sirupsen/logrus"
 
+	"gitea.com/gitea/act_runner/internal/pkg/actionmirror"
+	"gitea.com/gitea/act_runner/internal/pkg/actionstore"
 	"gitea.com/gitea/act_runner/internal/pkg/client"
 	"gitea.com/gitea/act_runner/internal/pkg/config"
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/hook"
+	"gitea.com/gitea/act_runner/internal/pkg/imagegc"
+	"gitea.com/gitea/act_runner/internal/pkg/imagepolicy"
 	"gitea.com/gitea/act_runner/internal/pkg/labels"
+	"gitea.com/gitea/act_runner/internal/pkg/logarchive"
+	"gitea.com/gitea/act_runner/internal/pkg/registry"
 	"gitea.com/gitea/act_runner/internal/pkg/report"
 	"gitea.com/gitea/act_runner/internal/pkg/ver"
 )
@@ -32,17 +42,23 @@ import (
 // Runner runs the pipeline.
 type Runner struct {
 	name string
+	uuid string
 
 	cfg *config.Config
 
-	client client.Client
-	labels labels.Labels
-	envs   map[string]string
+	client   client.Client
+	labels   labels.Labels
+	fallback string // fallback is the platform for jobs whose labels can't be satisfied
+	envs     map[string]string
+
+	registries registry.Registries
+	images     *imagegc.Tracker    // images records the images used by tasks, it could be nil
+	archive    *logarchive.Archive // archive stores the logs of tasks locally, it could be nil
 
 	runningTasks sync.Map
 }
 
-func NewRunner(cfg *config.Config, reg *config.Registration, cli client.Client) *Runner {
+func NewRunner(cfg *config.Config, reg *config.Registration, cli client.Client, registries registry.Registries, images *imagegc.Tracker) *Runner {
 	ls := labels.Labels{}
 	for _, v := range reg.Labels {
 		if l, err := labels.Parse(v); err == nil {
@@ -81,12 +97,30 @@ func NewRunner(cfg *config.Config, reg *config.Registration, cli client.Client)
 	envs["GITEA_ACTIONS"] = "true"
 	envs["GITEA_ACTIONS_RUNNER_VERSION"] = ver.Version()
 
+	fallback := ls.FallbackPlatform()
+	if cfg.Runner.FallbackImage != "" {
+		fallback = cfg.Runner.FallbackImage
+	}
+
+	var archive *logarchive.Archive
+	if cfg.Log.Archive.Dir != "" {
+		archive = &logarchive.Archive{
+			Dir:  cfg.Log.Archive.Dir,
+			Gzip: cfg.Log.Archive.Gzip,
+		}
+	}
+
 	return &Runner{
-		name:   reg.Name,
-		cfg:    cfg,
-		client: cli,
-		labels: ls,
-		envs:   envs,
+		name:       reg.Name,
+		uuid:       reg.UUID,
+		cfg:        cfg,
+		client:     cli,
+		labels:     ls,
+		fallback:   fallback,
+		envs:       envs,
+		registries: registries,
+		images:     images,
+		archive:    archive,
 	}
 }
 
@@ -100,6 +134,19 @@ func (r *Runner) Run(ctx context.Context, task *runnerv1.Task) error {
 	ctx, cancel := context.WithTimeout(ctx, r.cfg.Runner.Timeout)
 	defer cancel()
 	reporter := report.NewReporter(ctx, cancel, r.client, task)
+	reporter.SetOutputSecrets(report.OutputSecrets(r.cfg.Runner.OutputSecrets))
+	reporter.SetLeakDetection(r.cfg.Log.LeakDetection)
+	reporter.EnableOutbox(r.cfg.Runner.Outbox.Dir, r.cfg.Runner.Outbox.MaxMemoryRows)
+	if r.archive != nil {
+		archive, err := r.archive.Create(task.Context.Fields["repository"].GetStringValue(), task.Context.Fields["run_id"].GetStringValue(), task.Id)
+		if err != nil {
+			log.WithError(err).Errorf("failed to create the log archive of task %d", task.Id)
+		} else {
+			// closed after the reporter, which writes the last words
+			defer archive.Close()
+			reporter.SetArchive(archive)
+		}
+	}
 	var runErr error
 	defer func() {
 		lastWords := ""
@@ -115,6 +162,7 @@ func (r *Runner) Run(ctx context.Context, task *runnerv1.Task) error {
 }
 
 func (r *Runner) run(ctx context.Context, task *runnerv1.Task, reporter *report.Reporter) (err error) {
+	startedAt := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic: %v", r)
@@ -126,8 +174,8 @@ func (r *Runner) run(ctx context.Context, task *runnerv1.Task, reporter *report.
 		// replace with the real repo name {REPO} and runner name {RUNNER}
 		formattedRejectText := strings.ReplaceAll(r.cfg.Runner.RejectText, "{REPO}", task.Context.Fields["repository"].GetStringValue())
 		formattedRejectText = strings.ReplaceAll(formattedRejectText, "{RUNNER}", r.name)
-		log.Warnf(formattedRejectText)
-		reporter.Logf(formattedRejectText)
+		log.Warn(formattedRejectText)
+		reporter.Logf("%s", formattedRejectText)
 		return errors.New("repository not matched allowed_repos")
 	}
 
@@ -144,6 +192,31 @@ func (r *Runner) run(ctx context.Context, task *runnerv1.Task, reporter *report.
 	}
 	job := workflow.GetJob(jobID)
 	reporter.ResetSteps(len(job.Steps))
+	stepNames := make([]string, 0, len(job.Steps))
+	for _, step := range job.Steps {
+		stepNames = append(stepNames, step.String())
+	}
+	reporter.SetStepNames(stepNames)
+
+	label, ok := r.labels.Pick(job.RunsOn())
+	if !ok {
+		if r.cfg.Runner.RejectUnmatched {
+			reporter.Logf("runner %s can't satisfy the labels %v of the job, it only has %v", r.name, job.RunsOn(), r.labels.Names())
+			return fmt.Errorf("labels %v not matched", job.RunsOn())
+		}
+		reporter.Logf("runner %s can't satisfy the labels %v of the job, it will run on %s", r.name, job.RunsOn(), r.fallback)
+	}
+
+	policy, err := imagepolicy.New(r.cfg.Container.AllowedImages, r.cfg.Container.RequireDigest, r.cfg.Container.ImageLockFile)
+	if err != nil {
+		return err
+	}
+	if err := enforceImagePolicy(job, policy, reporter); err != nil {
+		return err
+	}
+	if err := applyRegistries(job, r.registries, reporter); err != nil {
+		return err
+	}
 
 	taskContext := task.Context.Fields
 
@@ -210,18 +283,108 @@ func (r *Runner) run(ctx context.Context, task *runnerv1.Task, reporter *report.
 		NoSkipCheckout:        true,
 		PresetGitHubContext:   preset,
 		EventJSON:             string(eventJSON),
-		ContainerNamePrefix:   fmt.Sprintf("GITEA-ACTIONS-TASK-%d", task.Id),
+		ContainerNamePrefix:   fmt.Sprintf("%s%d", containerNamePrefix, task.Id),
 		ContainerMaxLifetime:  maxLifetime,
 		ContainerNetworkMode:  container.NetworkMode(r.cfg.Container.Network),
 		ContainerOptions:      r.cfg.Container.Options,
 		ContainerDaemonSocket: r.cfg.Container.DockerHost,
 		Privileged:            r.cfg.Container.Privileged,
 		DefaultActionInstance: taskContext["gitea_default_actions_url"].GetStringValue(),
-		PlatformPicker:        r.labels.PickPlatform,
+		PlatformPicker:        r.pickPlatform,
 		Vars:                  task.Vars,
 		ValidVolumes:          r.cfg.Container.ValidVolumes,
 		InsecureSkipTLS:       r.cfg.Runner.Insecure,
 	}
+	if ok && label.Options != nil {
+		applyLabelOptions(runnerConfig, label.Options)
+	}
+
+	platform := r.pickPlatform(job.RunsOn())
+	if platform != labels.PlatformHost {
+		// the limits are applied after the options of the label, so they take precedence over them
+		res := resolveResources(&r.cfg.Container, preset.Repository, job.RunsOn(), label)
+		if err := applyResources(runnerConfig, job, res); err != nil {
+			return err
+		}
+		go r.watchOOM(ctx, runnerConfig.ContainerNamePrefix, res, reporter)
+	}
+
+	if platform == labels.PlatformHost || len(r.cfg.Runner.ActionRewrites) > 0 || !r.registries.Empty() {
+		// Host jobs, and jobs whose actions or their images are rewritten, fetch actions through the same cache,
+		// so the clones are shared between tasks, could be prepared by "act_runner mirror-sync", and are rewritten to the configured mirrors.
+		// The other jobs clone actions like act does.
+		cache := &lockingActionCache{
+			parent:   runner.GoGitActionCache{Path: r.cfg.Host.WorkdirParent},
+			insecure: r.cfg.Runner.Insecure,
+		}
+		defer cache.release()
+		runnerConfig.ActionCache = &actionmirror.Cache{
+			Parent:     cache,
+			Rules:      actionmirror.FromConfig(r.cfg.Runner.ActionRewrites),
+			DefaultURL: runnerConfig.DefaultActionInstance,
+		}
+	}
+
+	if r.cfg.Runner.Offline {
+		store := actionstore.Store{Dir: r.cfg.Runner.ActionStore}
+		runnerConfig.ForcePull = false
+		runnerConfig.ActionCache = newOfflineActionCache(store)
+		if err := checkOffline(ctx, job, platform, store, reporter); err != nil {
+			return err
+		}
+	}
+
+	if !r.registries.Empty() {
+		runnerConfig.ActionCache = &registryActionCache{
+			parent:     runnerConfig.ActionCache,
+			registries: r.registries,
+		}
+	}
+
+	if platform == labels.PlatformHost {
+		// Jobs running on the host share the file system, so every task gets its own directories,
+		// while the clones of actions in the action cache are still shared between tasks.
+		taskDir := filepath.Join(r.cfg.Host.WorkdirParent, fmt.Sprintf("task-%d", task.Id))
+		runnerConfig.Workdir = filepath.Join(taskDir, "workspace", filepath.FromSlash(preset.Repository))
+		runnerConfig.ActionCacheDir = taskDir
+		reporter.SetSummaryReader(hostSummaryReader(taskDir), int64(r.cfg.Runner.StepSummaryLimit))
+		if r.cfg.Host.CleanupWorkdir {
+			defer func() {
+				if err := os.RemoveAll(taskDir); err != nil {
+					log.WithError(err).Warnf("failed to clean up the working directory %s of task %d", taskDir, task.Id)
+				}
+			}()
+		}
+	} else {
+		jobName := job.Name
+		if jobName == "" {
+			jobName = jobID
+		}
+		taskLabels := map[string]string{
+			docker.LabelRunnerUUID: r.uuid,
+			docker.LabelRunnerName: r.name,
+			docker.LabelTaskID:     strconv.FormatInt(task.Id, 10),
+			docker.LabelRepository: preset.Repository,
+			docker.LabelRunID:      preset.RunID,
+			docker.LabelJob:        jobName,
+			docker.LabelStartedAt:  startedAt.Format(time.RFC3339),
+		}
+		applyTaskLabels(runnerConfig, job, taskLabels)
+		defer registerStepContainerOptions(runnerConfig.ContainerNamePrefix, runnerConfig.ContainerOptions)()
+		cleanup, err := createTaskResources(ctx, runnerConfig, workflow, jobID, taskLabels)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		readSummary, closeSummary := containerSummaryReader(task.Id)
+		defer closeSummary()
+		reporter.SetSummaryReader(readSummary, int64(r.cfg.Runner.StepSummaryLimit))
+
+		release := r.images.Hold(requiredImages(job, platform)...)
+		defer release()
+		go r.holdImages(ctx, runnerConfig.ContainerNamePrefix)
+	}
 
 	rr, err := runner.New(runnerConfig)
 	if err != nil {
@@ -238,11 +401,96 @@ func (r *Runner) run(ctx context.Context, task *runnerv1.Task, reporter *report.
 		ctx = runner.WithJobLoggerFactory(ctx, NullLogger{})
 	}
 
+	hookMeta := &hook.Metadata{
+		TaskID:     task.Id,
+		RunID:      preset.RunID,
+		Repository: preset.Repository,
+		Job:        jobID,
+		Event:      preset.EventName,
+		Runner:     r.name,
+	}
+	defer r.runPostJobHooks(ctx, hookMeta, reporter)
+	if err := r.runPreJobHooks(ctx, hookMeta, reporter); err != nil {
+		return err
+	}
+
 	execErr := executor(ctx)
 	reporter.SetOutputs(job.Outputs)
 	return execErr
 }
 
+// runPreJobHooks runs the configured pre-job hooks in order and streams their output into the job log.
+// The first failing hook stops the others and rejects the task.
+func (r *Runner) runPreJobHooks(ctx context.Context, meta *hook.Metadata, reporter *report.Reporter) error {
+	for _, path := range r.cfg.Hooks.PreJob {
+		reporter.Logf("running pre-job hook %s", path)
+		hookCtx, cancel := context.WithTimeout(ctx, r.cfg.Hooks.Timeout)
+		m := *meta
+		m.Stage = hook.StagePreJob
+		err := hook.Run(hookCtx, path, &m, reporter.Logf)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostJobHooks runs the configured post-job hooks in order.
+// They get their own context, so they still run when the task has been cancelled or has timed out.
+func (r *Runner) runPostJobHooks(ctx context.Context, meta *hook.Metadata, reporter *report.Reporter) {
+	if len(r.cfg.Hooks.PostJob) == 0 {
+		return
+	}
+
+	result := reporter.Result()
+	if result == runnerv1.Result_RESULT_UNSPECIFIED {
+		result = runnerv1.Result_RESULT_FAILURE
+		if ctx.Err() != nil {
+			result = runnerv1.Result_RESULT_CANCELLED
+		}
+	}
+
+	for _, path := range r.cfg.Hooks.PostJob {
+		hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.cfg.Hooks.Timeout)
+		m := *meta
+		m.Stage = hook.StagePostJob
+		m.Result = strings.ToLower(strings.TrimPrefix(result.String(), "RESULT_"))
+		logger := log.WithFields(log.Fields{"task": m.TaskID, "hook": path})
+		if err := hook.Run(hookCtx, path, &m, logger.Infof); err != nil {
+			logger.WithError(err).Error("post-job hook failed")
+		}
+		cancel()
+	}
+}
+
+// applyLabelOptions overrides the container config with the options of the picked label.
+func applyLabelOptions(cfg *runner.Config, opts *labels.Options) {
+	if opts.Privileged != nil {
+		cfg.Privileged = *opts.Privileged
+	}
+	if opts.Network != "" {
+		cfg.ContainerNetworkMode = container.NetworkMode(opts.Network)
+	}
+	if opts.DockerHost != "" {
+		cfg.ContainerDaemonSocket = opts.DockerHost
+	}
+	if flags := opts.ContainerOptions(); flags != "" {
+		cfg.ContainerOptions = strings.TrimSpace(cfg.ContainerOptions + " " + flags)
+	}
+}
+
+func (r *Runner) pickPlatform(runsOn []string) string {
+	platform := r.fallback
+	if label, ok := r.labels.Pick(runsOn); ok {
+		platform = label.Platform()
+	}
+	if platform != labels.PlatformHost {
+		platform = r.registries.Rewrite(platform)
+	}
+	return platform
+}
+
 func (r *Runner) Declare(ctx context.Context, labels []string) (*connect.Response[runnerv1.DeclareResponse], error) {
 	return r.client.Declare(ctx, connect.NewRequest(&runnerv1.DeclareRequest{
 		Version: ver.Version(),
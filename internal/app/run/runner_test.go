@@ -0,0 +1,77 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+	"gitea.com/gitea/act_runner/internal/pkg/hook"
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// writeHooks writes shell scripts which append their name and $GITEA_TASK_RESULT to a file, and exit with the given codes.
+func writeHooks(t *testing.T, codes ...int) ([]string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts in the tests")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	var paths []string
+	for i, code := range codes {
+		name := fmt.Sprintf("%c.sh", 'a'+i)
+		path := filepath.Join(dir, name)
+		script := fmt.Sprintf("#!/bin/sh\necho \"%s $GITEA_TASK_RESULT\" >> %s\nexit %d\n", name, out, code)
+		require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+		paths = append(paths, path)
+	}
+	return paths, out
+}
+
+func newHookTestReporter(ctx context.Context, t *testing.T) *report.Reporter {
+	t.Helper()
+	taskCtx, err := structpb.NewStruct(map[string]interface{}{})
+	require.NoError(t, err)
+	return report.NewReporter(ctx, func() {}, nil, &runnerv1.Task{Id: 1, Context: taskCtx})
+}
+
+func TestRunner_runPreJobHooks(t *testing.T) {
+	paths, out := writeHooks(t, 0, 1, 0)
+	r := &Runner{cfg: &config.Config{Hooks: config.Hooks{PreJob: paths, Timeout: time.Minute}}}
+
+	err := r.runPreJobHooks(context.Background(), &hook.Metadata{TaskID: 1}, newHookTestReporter(context.Background(), t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b.sh")
+
+	// the failing hook stops the others
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "a.sh \nb.sh \n", string(content))
+}
+
+func TestRunner_runPostJobHooks(t *testing.T) {
+	paths, out := writeHooks(t, 1, 0)
+	r := &Runner{cfg: &config.Config{Hooks: config.Hooks{PostJob: paths, Timeout: time.Minute}}}
+
+	// the hooks still run after the task has been cancelled, and a failing one doesn't stop the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.runPostJobHooks(ctx, &hook.Metadata{TaskID: 1}, newHookTestReporter(ctx, t))
+
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "a.sh cancelled\nb.sh cancelled\n", string(content))
+}
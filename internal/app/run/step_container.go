@@ -0,0 +1,47 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/runner"
+)
+
+// stepContainerOptions holds the container options of every running task, keyed by its container name prefix.
+// act creates the containers of "docker://" steps without any options, unlike the ones of docker actions,
+// so they would escape the resource limits and miss the task labels, they are added when such a container is created.
+var stepContainerOptions sync.Map
+
+func init() {
+	newContainer := runner.ContainerNewContainer
+	runner.ContainerNewContainer = func(input *container.NewContainerInput) container.ExecutionsEnvironment {
+		addStepContainerOptions(input)
+		return newContainer(input)
+	}
+}
+
+// registerStepContainerOptions makes the containers of "docker://" steps of the task with the container name prefix
+// created with the options, like the containers of docker actions, until the returned function is called.
+func registerStepContainerOptions(prefix, options string) (unregister func()) {
+	stepContainerOptions.Store(prefix, options)
+	return func() {
+		stepContainerOptions.Delete(prefix)
+	}
+}
+
+// addStepContainerOptions adds the options registered for the task of a step container to its input.
+func addStepContainerOptions(input *container.NewContainerInput) {
+	stepContainerOptions.Range(func(k, v any) bool {
+		prefix := k.(string)
+		// the name of the job container follows the prefix, joined by "_" which act replaces with "-" in the names of step containers
+		if rest, ok := strings.CutPrefix(input.Name, prefix); ok && (strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "_")) {
+			input.Options = appendOptions(input.Options, v.(string))
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+	"gitea.com/gitea/act_runner/internal/pkg/report"
+)
+
+// summaryFile is the file of $GITHUB_STEP_SUMMARY in the act path of the job, the same as runStep of act.
+const summaryFile = "workflow/SUMMARY.md"
+
+// containerActPath is the act path in job containers, the same as LinuxContainerEnvironmentExtensions of act.
+const containerActPath = "/var/run/act"
+
+// hostSummaryReader returns the reader of the step summaries of a job running on the host.
+// Act creates the act path of the job in a random directory under the task directory.
+func hostSummaryReader(taskDir string) report.SummaryReader {
+	return func(context.Context) (io.ReadCloser, error) {
+		matches, err := filepath.Glob(filepath.Join(taskDir, "*", "act", filepath.FromSlash(summaryFile)))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fs.ErrNotExist
+		}
+		return os.Open(matches[0])
+	}
+}
+
+// containerSummaryReader returns the reader of the step summaries of a job running in a container,
+// the job container is found by the labels of the task. The returned function releases the docker client.
+func containerSummaryReader(taskID int64) (report.SummaryReader, func()) {
+	var (
+		cli         *client.Client
+		containerID string
+	)
+	read := func(ctx context.Context) (io.ReadCloser, error) {
+		if cli == nil {
+			// the job container is on the docker host of the runner, like in watchOOM
+			c, err := docker.NewClient("")
+			if err != nil {
+				return nil, err
+			}
+			cli = c
+		}
+		if containerID == "" {
+			id, err := findJobContainer(ctx, cli, taskID)
+			if err != nil {
+				return nil, err
+			}
+			containerID = id
+		}
+
+		rc, _, err := cli.CopyFromContainer(ctx, containerID, path.Join(containerActPath, summaryFile))
+		if client.IsErrNotFound(err) {
+			return nil, fs.ErrNotExist
+		} else if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(rc)
+		if _, err := tr.Next(); err != nil {
+			rc.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{tr, rc}, nil
+	}
+	release := func() {
+		if cli != nil {
+			cli.Close()
+		}
+	}
+	return read, release
+}
+
+// findJobContainer returns the ID of the job container of the task.
+func findJobContainer(ctx context.Context, cli *client.Client, taskID int64) (string, error) {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", docker.LabelTaskID+"="+strconv.FormatInt(taskID, 10))),
+	})
+	if err != nil {
+		return "", err
+	}
+	// the containers of docker actions are named after the job container with a suffix, so the job container has the shortest name
+	id, name := "", ""
+	for _, c := range containers {
+		if _, ok := c.Labels[docker.LabelService]; ok || len(c.Names) == 0 {
+			continue
+		}
+		if id == "" || len(c.Names[0]) < len(name) {
+			id, name = c.ID, c.Names[0]
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("the job container of task %d: %w", taskID, fs.ErrNotExist)
+	}
+	return id, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+)
+
+// containerNamePrefix is the prefix of the names of the containers, networks and volumes created for a task,
+// it's followed by the task id.
+const containerNamePrefix = "GITEA-ACTIONS-TASK-"
+
+// labelOptions returns the labels as container options.
+func labelOptions(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	opts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		opts = append(opts, "--label="+strconv.Quote(k+"="+labels[k]))
+	}
+	return strings.Join(opts, " ")
+}
+
+// applyTaskLabels sets the labels to the job container and the service containers,
+// the service containers are labeled with their IDs as well.
+// The containers of docker actions and "docker://" steps get the labels from the options of the job container,
+// see registerStepContainerOptions for the latter.
+func applyTaskLabels(cfg *runner.Config, job *model.Job, labels map[string]string) {
+	cfg.ContainerOptions = appendOptions(cfg.ContainerOptions, labelOptions(labels))
+	updateServices(job, func(name string, spec *model.ContainerSpec) {
+		serviceLabels := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			serviceLabels[k] = v
+		}
+		serviceLabels[docker.LabelService] = name
+		spec.Options = appendOptions(spec.Options, labelOptions(serviceLabels))
+	})
+}
+
+// containerNamePattern matches the characters which act replaces in the names of containers.
+var containerNamePattern = regexp.MustCompile("[^a-zA-Z0-9-]")
+
+// jobContainerName returns the name act gives to the job container, the volumes and the network of a job.
+// It's false if the name can't be known in advance, since act interpolates the name of the job.
+func jobContainerName(prefix string, workflow *model.Workflow, jobID string) (string, bool) {
+	name := workflow.GetJob(jobID).Name
+	if name == "" {
+		name = jobID
+	}
+	if strings.Contains(name, "${{") {
+		return "", false
+	}
+
+	// the same as createSimpleContainerName of act
+	parts := make([]string, 0, 3)
+	for _, v := range []string{prefix, "WORKFLOW-" + workflow.Name, "JOB-" + name} {
+		v = containerNamePattern.ReplaceAllString(v, "-")
+		v = strings.Trim(v, "-")
+		for strings.Contains(v, "--") {
+			v = strings.ReplaceAll(v, "--", "-")
+		}
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, "_"), true
+}
+
+// createTaskResources creates the network and the volumes of the job with the labels, before act creates them without labels.
+// Act uses existing ones with the same names, and removes them when the job finishes.
+// The returned function removes them in case act doesn't.
+func createTaskResources(ctx context.Context, cfg *runner.Config, workflow *model.Workflow, jobID string, labels map[string]string) (func(), error) {
+	name, ok := jobContainerName(cfg.ContainerNamePrefix, workflow, jobID)
+	if !ok {
+		return func() {}, nil
+	}
+	cli, err := docker.NewClient("")
+	if err != nil {
+		return nil, err
+	}
+
+	var created []docker.Resource
+	cleanup := func() {
+		defer cli.Close()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		for _, r := range created {
+			if err := docker.RemoveResource(ctx, cli, r); err != nil && !client.IsErrNotFound(err) {
+				log.WithError(err).Warnf("failed to remove %s %s", r.Kind, r.Name)
+			}
+		}
+	}
+
+	for _, v := range []string{name, name + "-env"} {
+		if _, err := cli.VolumeCreate(ctx, volume.CreateOptions{Name: v, Labels: labels}); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("create volume %s: %w", v, err)
+		}
+		created = append(created, docker.Resource{Kind: docker.KindVolume, ID: v, Name: v})
+	}
+	if cfg.ContainerNetworkMode == "" {
+		// the same name as networkNameForGitea of act
+		network := fmt.Sprintf("%s-%s-network", name, jobID)
+		resp, err := cli.NetworkCreate(ctx, network, types.NetworkCreate{
+			Driver: "bridge",
+			Scope:  "local",
+			Labels: labels,
+		})
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("create network %s: %w", network, err)
+		}
+		created = append(created, docker.Resource{Kind: docker.KindNetwork, ID: resp.ID, Name: network})
+	}
+	return cleanup, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package run
+
+import (
+	"testing"
+
+	"github.com/nektos/act/pkg/container"
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+)
+
+func TestLabelOptions(t *testing.T) {
+	assert.Equal(t, `--label="gitea.actions.job=build (linux)" --label="gitea.actions.task.id=12"`, labelOptions(map[string]string{
+		"gitea.actions.task.id": "12",
+		"gitea.actions.job":     "build (linux)",
+	}))
+}
+
+func TestJobContainerName(t *testing.T) {
+	workflow := &model.Workflow{
+		Name: "CI / test",
+		Jobs: map[string]*model.Job{
+			"test":    {Name: "Test on linux (x64)"},
+			"build":   {},
+			"dynamic": {Name: "Test ${{ matrix.os }}"},
+		},
+	}
+
+	name, ok := jobContainerName("GITEA-ACTIONS-TASK-12", workflow, "test")
+	assert.True(t, ok)
+	assert.Equal(t, "GITEA-ACTIONS-TASK-12_WORKFLOW-CI-test_JOB-Test-on-linux-x64", name)
+
+	name, ok = jobContainerName("GITEA-ACTIONS-TASK-12", workflow, "build")
+	assert.True(t, ok)
+	assert.Equal(t, "GITEA-ACTIONS-TASK-12_WORKFLOW-CI-test_JOB-build", name)
+
+	_, ok = jobContainerName("GITEA-ACTIONS-TASK-12", workflow, "dynamic")
+	assert.False(t, ok)
+
+	// the names are the ones act gives, which are only exposed through the volumes of the job container
+	for _, jobID := range []string{"test", "build"} {
+		name, _ := jobContainerName("GITEA-ACTIONS-TASK-12", workflow, jobID)
+		run := &model.Run{Workflow: workflow, JobID: jobID}
+		rc := &runner.RunContext{
+			Name:   run.String(),
+			Config: &runner.Config{ContainerNamePrefix: "GITEA-ACTIONS-TASK-12", ContainerDaemonSocket: "-"},
+			Run:    run,
+		}
+		_, mounts := rc.GetBindsAndMounts()
+		assert.Contains(t, mounts, name+"-env")
+	}
+}
+
+func TestApplyTaskLabels(t *testing.T) {
+	job := &model.Job{Services: map[string]*model.ContainerSpec{"db": {Image: "postgres"}}}
+	cfg := &runner.Config{ContainerNamePrefix: "GITEA-ACTIONS-TASK-12", ContainerOptions: "--cpus=2"}
+	applyTaskLabels(cfg, job, map[string]string{docker.LabelTaskID: "12"})
+	assert.Equal(t, `--cpus=2 --label="gitea.actions.task.id=12"`, cfg.ContainerOptions)
+	assert.Equal(t, `--label="gitea.actions.service=db" --label="gitea.actions.task.id=12"`, job.Services["db"].Options)
+
+	// the containers of "docker://" steps are labeled too
+	defer registerStepContainerOptions(cfg.ContainerNamePrefix, cfg.ContainerOptions)()
+	input := &container.NewContainerInput{Name: "GITEA-ACTIONS-TASK-12-WORKFLOW-test-JOB-job1_STEP-build"}
+	addStepContainerOptions(input)
+	assert.Contains(t, input.Options, `--label="gitea.actions.task.id=12"`)
+}
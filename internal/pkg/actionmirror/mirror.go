@@ -0,0 +1,161 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package actionmirror resolves actions from mirrors instead of their upstream repositories.
+package actionmirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/nektos/act/pkg/runner"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+// Rule rewrites the clone URLs of actions.
+// From is either a URL prefix like "https://github.com/foo", or an action prefix like "actions/checkout" or "actions",
+// which matches the "<owner>/<repo>" of the action on any instance.
+// To replaces the matched part, it's a URL or a local directory of bare repositories.
+type Rule struct {
+	From string
+	To   string
+}
+
+// Rules are applied in order, the first matching rule wins.
+type Rules []Rule
+
+// Rewrite returns the URL to clone instead of the given one, or the URL itself if no rule matches.
+func (rs Rules) Rewrite(url string) string {
+	for _, r := range rs {
+		from := strings.TrimSuffix(r.From, "/")
+		if from == "" {
+			continue
+		}
+		subject := url
+		if !strings.Contains(from, "://") {
+			subject = repoName(url)
+		}
+		if subject == from {
+			return r.To
+		}
+		if strings.HasPrefix(subject, from+"/") {
+			return strings.TrimSuffix(r.To, "/") + subject[len(from):]
+		}
+	}
+	return url
+}
+
+// repoName returns the "<owner>/<repo>" of a clone URL.
+func repoName(url string) string {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git"), "/")
+	if len(parts) < 2 {
+		return url
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// isLocal reports whether the target of a rule is a local directory rather than a URL.
+func isLocal(target string) bool {
+	return !strings.Contains(target, "://") && filepath.IsAbs(target)
+}
+
+// installFileTransport serves local repositories in process, so cloning them doesn't require the git executables.
+var installFileTransport = sync.OnceFunc(func() {
+	client.InstallProtocol("file", server.DefaultServer)
+})
+
+// Cache is an ActionCache which fetches actions from the URLs rewritten by the rules.
+type Cache struct {
+	Parent runner.ActionCache
+	Rules  Rules
+	// DefaultURL is the instance of the actions used without a URL, like "https://github.com".
+	// act passes such actions as "/<owner>/<repo>" to an ActionCache.
+	DefaultURL string
+}
+
+func (c *Cache) Fetch(ctx context.Context, cacheDir, url, ref, token string) (string, error) {
+	if strings.HasPrefix(url, "/") && c.DefaultURL != "" {
+		url = baseURL(c.DefaultURL) + url
+	}
+	url = c.Rules.Rewrite(url)
+	if isLocal(url) {
+		if _, err := os.Stat(url); os.IsNotExist(err) {
+			if _, err := os.Stat(url + ".git"); err == nil {
+				url += ".git"
+			}
+		}
+		installFileTransport()
+	}
+	return c.Parent.Fetch(ctx, cacheDir, url, ref, token)
+}
+
+func (c *Cache) GetTarArchive(ctx context.Context, cacheDir, sha, includePrefix string) (io.ReadCloser, error) {
+	return c.Parent.GetTarArchive(ctx, cacheDir, sha, includePrefix)
+}
+
+// baseURL returns the URL of an instance without the trailing slash,
+// an instance without a scheme like "gitea.com" is cloned over HTTPS, like act does.
+func baseURL(instance string) string {
+	instance = strings.TrimSuffix(instance, "/")
+	if !strings.HasPrefix(instance, "http://") && !strings.HasPrefix(instance, "https://") {
+		instance = "https://" + instance
+	}
+	return instance
+}
+
+// Action is a remote action or reusable workflow used by a workflow.
+type Action struct {
+	URL      string // URL is the clone URL of the repository.
+	CacheDir string // CacheDir is the key of the repository in an ActionCache, act uses "<owner>/<repo>" for actions.
+	Ref      string
+}
+
+func (a Action) String() string {
+	return a.URL + "@" + a.Ref
+}
+
+// ParseAction parses the "uses" of a step or a job, like "actions/checkout@v4" or "https://gitea.com/owner/repo/path@v1".
+// Actions without a URL come from defaultURL.
+func ParseAction(uses, defaultURL string) (Action, error) {
+	base := baseURL(defaultURL)
+	for _, scheme := range []string{"https://", "http://"} {
+		if strings.HasPrefix(uses, scheme) {
+			host, rest, _ := strings.Cut(strings.TrimPrefix(uses, scheme), "/")
+			base, uses = scheme+host, rest
+			break
+		}
+	}
+	path, ref, ok := strings.Cut(uses, "@")
+	parts := strings.Split(path, "/")
+	if !ok || ref == "" || len(parts) < 2 || parts[0] == "" || parts[1] == "" || parts[0] == "." || parts[0] == ".." {
+		return Action{}, fmt.Errorf("invalid action %q", uses)
+	}
+	name := parts[0] + "/" + parts[1]
+	cacheDir := name
+	if len(parts) > 3 && (parts[2] == ".gitea" || parts[2] == ".github") && parts[3] == "workflows" {
+		// act caches the repositories of reusable workflows per ref
+		cacheDir = name + "@" + ref
+	}
+	return Action{
+		URL:      base + "/" + name,
+		CacheDir: cacheDir,
+		Ref:      ref,
+	}, nil
+}
+
+// FromConfig returns the rules of the config.
+func FromConfig(cfgs []config.ActionRewrite) Rules {
+	rules := make(Rules, 0, len(cfgs))
+	for _, c := range cfgs {
+		rules = append(rules, Rule{From: c.From, To: c.To})
+	}
+	return rules
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actionmirror
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_Rewrite(t *testing.T) {
+	rules := Rules{
+		{From: "actions/checkout", To: "/srv/actions/checkout.git"},
+		{From: "actions", To: "https://gitea.internal/actions-mirror/"},
+		{From: "https://github.com/", To: "https://gitea.internal/github"},
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://github.com/actions/checkout", want: "/srv/actions/checkout.git"},
+		{url: "https://gitea.com/actions/setup-go", want: "https://gitea.internal/actions-mirror/setup-go"},
+		{url: "https://github.com/foo/bar", want: "https://gitea.internal/github/foo/bar"},
+		{url: "https://github.community/foo/bar", want: "https://github.community/foo/bar"},
+		{url: "https://gitea.com/foo/bar", want: "https://gitea.com/foo/bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules.Rewrite(tt.url))
+		})
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		uses    string
+		want    Action
+		wantErr bool
+	}{
+		{
+			uses: "actions/checkout@v4",
+			want: Action{URL: "https://github.com/actions/checkout", CacheDir: "actions/checkout", Ref: "v4"},
+		},
+		{
+			uses: "https://gitea.com/owner/repo/sub/dir@main",
+			want: Action{URL: "https://gitea.com/owner/repo", CacheDir: "owner/repo", Ref: "main"},
+		},
+		{
+			uses: "owner/repo/.gitea/workflows/build.yml@v1",
+			want: Action{URL: "https://github.com/owner/repo", CacheDir: "owner/repo@v1", Ref: "v1"},
+		},
+		{uses: "actions/checkout", wantErr: true},
+		{uses: "./.gitea/actions/local@v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			got, err := ParseAction(tt.uses, "https://github.com/")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// an instance without a scheme is cloned over HTTPS
+	got, err := ParseAction("actions/checkout@v4", "gitea.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://gitea.com/actions/checkout", got.URL)
+}
+
+type recordingCache struct {
+	urls []string
+}
+
+func (c *recordingCache) Fetch(_ context.Context, _, url, _, _ string) (string, error) {
+	c.urls = append(c.urls, url)
+	return "sha", nil
+}
+
+func (c *recordingCache) GetTarArchive(context.Context, string, string, string) (io.ReadCloser, error) {
+	return nil, io.EOF
+}
+
+func TestCache_Fetch(t *testing.T) {
+	parent := &recordingCache{}
+	for _, defaultURL := range []string{"https://github.com/", "github.com", "http://gitea.internal"} {
+		c := &Cache{Parent: parent, DefaultURL: defaultURL}
+		_, err := c.Fetch(context.Background(), "actions/checkout", "/actions/checkout", "v4", "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{
+		"https://github.com/actions/checkout",
+		"https://github.com/actions/checkout",
+		"http://gitea.internal/actions/checkout",
+	}, parent.urls)
+}
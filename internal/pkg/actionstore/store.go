@@ -0,0 +1,256 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package actionstore keeps actions as plain directories, for runners which can't clone them.
+package actionstore
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// actionPattern matches the references of remote actions and reusable workflows, like "owner/repo/path@ref".
+var actionPattern = regexp.MustCompile(`^([^/@]+)/([^/@]+)(/[^@]*)?@(.+)$`)
+
+// ParseAction returns the repository, like "owner/repo", and the ref of an action referenced by a "uses" key.
+// A reference with a URL, like "https://gitea.com/owner/repo@v1", is supported as well.
+func ParseAction(uses string) (repo, ref string, err error) {
+	for _, scheme := range []string{"https://", "http://"} {
+		if strings.HasPrefix(uses, scheme) {
+			_, uses, _ = strings.Cut(strings.TrimPrefix(uses, scheme), "/")
+			break
+		}
+	}
+	matches := actionPattern.FindStringSubmatch(uses)
+	if matches == nil {
+		return "", "", fmt.Errorf("invalid action %q", uses)
+	}
+	// "." and ".." would resolve outside of the directory of the action in the store
+	for _, segment := range append(strings.Split(matches[1]+"/"+matches[2]+matches[3], "/"), matches[4]) {
+		if segment == "." || segment == ".." {
+			return "", "", fmt.Errorf("invalid action %q", uses)
+		}
+	}
+	return matches[1] + "/" + matches[2], matches[4], nil
+}
+
+// Store is a directory containing actions, laid out as "<owner>/<repo>/<ref>".
+// A "/" in a ref is escaped as "%2F", like "owner/repo/releases%2Fv1".
+type Store struct {
+	Dir string
+}
+
+// Path returns the directory of the action at the ref.
+func (s Store) Path(repo, ref string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(repo), url.PathEscape(ref))
+}
+
+// Has reports whether the store has the action at the ref.
+func (s Store) Has(repo, ref string) bool {
+	stat, err := os.Stat(s.Path(repo, ref))
+	return err == nil && stat.IsDir()
+}
+
+// Import extracts a tar archive, which could be gzipped, into the store and returns the imported actions.
+// If action is empty, the archive is laid out like the store, so it could contain many actions.
+// Otherwise, the archive is the content of the given action, like "owner/repo@v1",
+// and a single top-level directory, like the one of a source archive, is stripped.
+// Actions already in the store are replaced.
+func (s Store) Import(r io.Reader, action string) ([]string, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(s.Dir, ".import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extract(r, tmp); err != nil {
+		return nil, err
+	}
+
+	if action != "" {
+		repo, ref, err := ParseAction(action)
+		if err != nil {
+			return nil, err
+		}
+		root := tmp
+		if entries, err := os.ReadDir(tmp); err == nil && len(entries) == 1 && entries[0].IsDir() {
+			root = filepath.Join(tmp, entries[0].Name())
+		}
+		if err := s.replace(root, s.Path(repo, ref)); err != nil {
+			return nil, err
+		}
+		return []string{repo + "@" + ref}, nil
+	}
+
+	refs, err := filepath.Glob(filepath.Join(tmp, "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	var imported []string
+	for _, dir := range refs {
+		if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(tmp, dir)
+		if err != nil {
+			return imported, err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		ref, err := url.PathUnescape(parts[2])
+		if err != nil {
+			return imported, fmt.Errorf("invalid ref %q: %w", parts[2], err)
+		}
+		repo := parts[0] + "/" + parts[1]
+		if err := s.replace(dir, s.Path(repo, ref)); err != nil {
+			return imported, err
+		}
+		imported = append(imported, repo+"@"+ref)
+	}
+	if len(imported) == 0 {
+		return nil, errors.New("no actions found, the archive should contain directories like \"<owner>/<repo>/<ref>\"")
+	}
+	return imported, nil
+}
+
+// replace moves src to dst, removing what is at dst.
+func (s Store) replace(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// extract writes the entries of a tar archive, which could be gzipped, to dir.
+// Entries which would end up outside of dir are refused.
+func extract(r io.Reader, dir string) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			if filepath.Clean(name) == "." {
+				continue
+			}
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		// an entry under a link could be written outside of dir through it
+		if err := checkParents(dir, name); err != nil {
+			return fmt.Errorf("invalid path in archive: %s: %w", hdr.Name, err)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !isLocalLink(name, filepath.FromSlash(hdr.Linkname)) {
+				return fmt.Errorf("invalid link in archive: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			// other entries, like the global header of a source archive, are not part of the action
+		}
+	}
+}
+
+// checkParents returns an error if a parent directory of name in dir is a link.
+func checkParents(dir, name string) error {
+	parent := dir
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		parent = filepath.Join(parent, part)
+		fi, err := os.Lstat(parent)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a link", filepath.ToSlash(parent[len(dir)+1:]))
+		}
+	}
+	return nil
+}
+
+// isLocalLink returns whether the link name with the target linkname points inside of the archive.
+// The ".." in the target are only allowed at its start: since the parents of a link are never links,
+// they are resolved lexically there, while after a link they would be resolved from where the link points to.
+func isLocalLink(name, linkname string) bool {
+	if filepath.IsAbs(linkname) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), linkname)) {
+		return false
+	}
+	down := false
+	for _, part := range strings.Split(linkname, string(filepath.Separator)) {
+		switch part {
+		case "..":
+			if down {
+				return false
+			}
+		case ".", "":
+		default:
+			down = true
+		}
+	}
+	return true
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actionstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		uses    string
+		repo    string
+		ref     string
+		wantErr bool
+	}{
+		{uses: "actions/checkout@v4", repo: "actions/checkout", ref: "v4"},
+		{uses: "owner/repo/path/to/action@releases/v1", repo: "owner/repo", ref: "releases/v1"},
+		{uses: "https://gitea.com/owner/repo@main", repo: "owner/repo", ref: "main"},
+		{uses: "owner/repo/.gitea/workflows/build.yml@v1", repo: "owner/repo", ref: "v1"},
+		{uses: "actions/checkout", wantErr: true},
+		{uses: "./local", wantErr: true},
+		{uses: "../repo@v1", wantErr: true},
+		{uses: "owner/..@v1", wantErr: true},
+		{uses: "owner/repo/../../x@v1", wantErr: true},
+		{uses: "owner/repo@..", wantErr: true},
+		{uses: "https://gitea.com/../repo@v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			repo, ref, err := ParseAction(tt.uses)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.repo, repo)
+			assert.Equal(t, tt.ref, ref)
+		})
+	}
+}
+
+func archive(t *testing.T, gzipped bool, files map[string]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	var tw *tar.Writer
+	var gw *gzip.Writer
+	if gzipped {
+		gw = gzip.NewWriter(buf)
+		tw = tar.NewWriter(gw)
+	} else {
+		tw = tar.NewWriter(buf)
+	}
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if gw != nil {
+		require.NoError(t, gw.Close())
+	}
+	return buf
+}
+
+func TestStore_Import(t *testing.T) {
+	store := Store{Dir: t.TempDir()}
+
+	imported, err := store.Import(archive(t, true, map[string]string{
+		"actions/checkout/v4/action.yml":           "name: checkout",
+		"owner/repo/releases%2Fv1/sub/action.yaml": "name: sub",
+	}), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"actions/checkout@v4", "owner/repo@releases/v1"}, imported)
+	assert.True(t, store.Has("actions/checkout", "v4"))
+	assert.True(t, store.Has("owner/repo", "releases/v1"))
+	assert.False(t, store.Has("actions/checkout", "v3"))
+
+	imported, err = store.Import(archive(t, false, map[string]string{
+		"setup-go-5.0.0/action.yml": "name: setup-go",
+	}), "actions/setup-go@v5")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"actions/setup-go@v5"}, imported)
+	content, err := os.ReadFile(filepath.Join(store.Path("actions/setup-go", "v5"), "action.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "name: setup-go", string(content))
+
+	_, err = store.Import(archive(t, false, map[string]string{
+		"../escape/action.yml": "",
+	}), "actions/escape@v1")
+	assert.Error(t, err)
+
+	_, err = store.Import(archive(t, false, map[string]string{
+		"action.yml": "",
+	}), "")
+	assert.Error(t, err)
+}
+
+func TestExtract_Links(t *testing.T) {
+	extractEntries := func(t *testing.T, headers ...*tar.Header) (string, error) {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, hdr := range headers {
+			require.NoError(t, tw.WriteHeader(hdr))
+		}
+		require.NoError(t, tw.Close())
+		dir := filepath.Join(t.TempDir(), "action")
+		require.NoError(t, os.Mkdir(dir, 0o755))
+		return dir, extract(buf, dir)
+	}
+	link := func(name, target string) *tar.Header {
+		return &tar.Header{Name: name, Linkname: target, Typeflag: tar.TypeSymlink}
+	}
+
+	dir, err := extractEntries(t,
+		&tar.Header{Name: "lib/index.js", Mode: 0o644, Typeflag: tar.TypeReg},
+		link("node_modules/.bin/run", "../../lib/index.js"),
+		link("current", "lib"),
+	)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "node_modules/.bin/run"))
+	assert.FileExists(t, filepath.Join(dir, "current/index.js"))
+
+	// "a/b/c" would point to the parent of dir, since "a/b" points to dir itself
+	dir, err = extractEntries(t,
+		link("a/b", ".."),
+		link("a/b/c", ".."),
+		&tar.Header{Name: "a/b/c/x", Mode: 0o644, Typeflag: tar.TypeReg},
+	)
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(dir), "x"))
+	assert.NoFileExists(t, filepath.Join(dir, "c"))
+
+	// ".." after a link is resolved from where the link points to
+	_, err = extractEntries(t,
+		link("a", "."),
+		link("b", "a/.."),
+	)
+	assert.Error(t, err)
+
+	_, err = extractEntries(t, link("a", "../outside"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes.
+// It can be written as a plain number or a human-readable string like "512MB" or "10GB" in the config file, or -1.
+type ByteSize int64
+
+func (s *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	var str string
+	if err := node.Decode(&str); err != nil {
+		return err
+	}
+	switch str {
+	case "":
+		*s = 0
+		return nil
+	case "-1":
+		// some limits, like the one of swap, use -1 for unlimited
+		*s = -1
+		return nil
+	}
+	size, err := units.RAMInBytes(str)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %w", str, err)
+	}
+	*s = ByteSize(size)
+	return nil
+}
+
+func (s ByteSize) String() string {
+	return units.BytesSize(float64(s))
+}
@@ -16,24 +16,55 @@ import (
 
 // Log represents the configuration for logging.
 type Log struct {
-	Level string `yaml:"level"` // Level indicates the logging level.
+	Level         string     `yaml:"level"`          // Level indicates the logging level.
+	Archive       LogArchive `yaml:"archive"`        // Archive represents the configuration for the local archive of task logs.
+	LeakDetection bool       `yaml:"leak_detection"` // LeakDetection indicates whether what looks like credentials is redacted from task logs, besides the secrets.
+}
+
+// LogArchive represents the configuration for the local archive of task logs.
+type LogArchive struct {
+	Dir  string `yaml:"dir"`  // Dir specifies the directory to store the log of every task in, empty means not to archive the logs.
+	Gzip bool   `yaml:"gzip"` // Gzip indicates whether the log files are gzipped.
 }
 
 // Runner represents the configuration for the runner.
 type Runner struct {
-	File            string            `yaml:"file"`             // File specifies the file path for the runner.
-	Capacity        int               `yaml:"capacity"`         // Capacity specifies the capacity of the runner.
-	Envs            map[string]string `yaml:"envs"`             // Envs stores environment variables for the runner.
-	EnvFile         string            `yaml:"env_file"`         // EnvFile specifies the path to the file containing environment variables for the runner.
-	Timeout         time.Duration     `yaml:"timeout"`          // Timeout specifies the duration for runner timeout.
-	ShutdownTimeout time.Duration     `yaml:"shutdown_timeout"` // ShutdownTimeout specifies the duration to wait for running jobs to complete during a shutdown of the runner.
-	Insecure        bool              `yaml:"insecure"`         // Insecure indicates whether the runner operates in an insecure mode.
-	FetchTimeout    time.Duration     `yaml:"fetch_timeout"`    // FetchTimeout specifies the timeout duration for fetching resources.
-	FetchInterval   time.Duration     `yaml:"fetch_interval"`   // FetchInterval specifies the interval duration for fetching resources.
-	Labels          []string          `yaml:"labels"`           // Labels specify the labels of the runner. Labels are declared on each startup
-	AllowedRepos    []string          `yaml:"allowed_repos"`    // AllowedRepos specify the repositories that the runner is allowed to run jobs for.
-	BlacklistMode	  bool              `yaml:"blacklist_mode"`   // BlacklistMode indicates whether the runner operates in blacklist mode.
-	RejectText	  string            `yaml:"reject_text"`      // RejectText specifies the text to be displayed when a job is rejected.
+	File             string            `yaml:"file"`               // File specifies the file path for the runner.
+	Capacity         int               `yaml:"capacity"`           // Capacity specifies the capacity of the runner.
+	Envs             map[string]string `yaml:"envs"`               // Envs stores environment variables for the runner.
+	EnvFile          string            `yaml:"env_file"`           // EnvFile specifies the path to the file containing environment variables for the runner.
+	Timeout          time.Duration     `yaml:"timeout"`            // Timeout specifies the duration for runner timeout.
+	ShutdownTimeout  time.Duration     `yaml:"shutdown_timeout"`   // ShutdownTimeout specifies the duration to wait for running jobs to complete during a shutdown of the runner.
+	Insecure         bool              `yaml:"insecure"`           // Insecure indicates whether the runner operates in an insecure mode.
+	FetchTimeout     time.Duration     `yaml:"fetch_timeout"`      // FetchTimeout specifies the timeout duration for fetching resources.
+	FetchInterval    time.Duration     `yaml:"fetch_interval"`     // FetchInterval specifies the interval duration for fetching resources.
+	Labels           []string          `yaml:"labels"`             // Labels specify the labels of the runner. Labels are declared on each startup
+	AllowedRepos     []string          `yaml:"allowed_repos"`      // AllowedRepos specify the repositories that the runner is allowed to run jobs for.
+	BlacklistMode    bool              `yaml:"blacklist_mode"`     // BlacklistMode indicates whether the runner operates in blacklist mode.
+	RejectText       string            `yaml:"reject_text"`        // RejectText specifies the text to be displayed when a job is rejected.
+	FallbackImage    string            `yaml:"fallback_image"`     // FallbackImage specifies the image for jobs whose labels can't be satisfied by the runner.
+	RejectUnmatched  bool              `yaml:"reject_unmatched"`   // RejectUnmatched indicates whether jobs whose labels can't be satisfied by the runner are rejected instead of using the fallback.
+	AutoLabels       bool              `yaml:"auto_labels"`        // AutoLabels indicates whether host labels describing the capabilities of the host are added on startup.
+	AutoLabelsPrefix string            `yaml:"auto_labels_prefix"` // AutoLabelsPrefix specifies the prefix of the names of the auto-detected labels.
+	Offline          bool              `yaml:"offline"`            // Offline indicates whether the runner never pulls images or clones actions, it only uses the local ones.
+	ActionStore      string            `yaml:"action_store"`       // ActionStore specifies the directory of the actions used in offline mode.
+	ActionRewrites   []ActionRewrite   `yaml:"action_rewrites"`    // ActionRewrites specify where actions are cloned from instead of their upstream repositories.
+	Outbox           Outbox            `yaml:"outbox"`             // Outbox represents the configuration for the outbox of what tasks couldn't report to the Gitea instance.
+	StepSummaryLimit ByteSize          `yaml:"step_summary_limit"` // StepSummaryLimit specifies the size of the summary of a step written to $GITHUB_STEP_SUMMARY which is reported at most.
+	OutputSecrets    string            `yaml:"output_secrets"`     // OutputSecrets specifies what to do with job outputs containing secrets: "redact", "drop" or "fail".
+}
+
+// ActionRewrite represents a rule rewriting the clone URLs of actions.
+type ActionRewrite struct {
+	From string `yaml:"from"` // From specifies a URL prefix like "https://github.com/foo", or an action prefix like "actions/checkout" or "actions".
+	To   string `yaml:"to"`   // To specifies the URL or the local directory of bare repositories replacing the matched part.
+}
+
+// Outbox represents the configuration for the outbox of the logs and states which tasks couldn't report to the Gitea instance.
+type Outbox struct {
+	Dir           string        `yaml:"dir"`             // Dir specifies the directory of the outbox, every task has a sub directory in it when needed.
+	MaxMemoryRows int           `yaml:"max_memory_rows"` // MaxMemoryRows specifies how many log rows of a task may stay in memory before they spill to the outbox.
+	Interval      time.Duration `yaml:"interval"`        // Interval specifies the interval between two attempts to report what's in the outbox.
 }
 
 // Cache represents the configuration for caching.
@@ -47,20 +78,111 @@ type Cache struct {
 
 // Container represents the configuration for the container.
 type Container struct {
-	Network       string   `yaml:"network"`        // Network specifies the network for the container.
-	NetworkMode   string   `yaml:"network_mode"`   // Deprecated: use Network instead. Could be removed after Gitea 1.20
-	Privileged    bool     `yaml:"privileged"`     // Privileged indicates whether the container runs in privileged mode.
-	Options       string   `yaml:"options"`        // Options specifies additional options for the container.
-	WorkdirParent string   `yaml:"workdir_parent"` // WorkdirParent specifies the parent directory for the container's working directory.
-	ValidVolumes  []string `yaml:"valid_volumes"`  // ValidVolumes specifies the volumes (including bind mounts) can be mounted to containers.
-	DockerHost    string   `yaml:"docker_host"`    // DockerHost specifies the Docker host. It overrides the value specified in environment variable DOCKER_HOST.
-	ForcePull     bool     `yaml:"force_pull"`     // Pull docker image(s) even if already present
-	ForceRebuild  bool     `yaml:"force_rebuild"`  // Rebuild docker image(s) even if already present
+	Network            string             `yaml:"network"`              // Network specifies the network for the container.
+	NetworkMode        string             `yaml:"network_mode"`         // Deprecated: use Network instead. Could be removed after Gitea 1.20
+	Privileged         bool               `yaml:"privileged"`           // Privileged indicates whether the container runs in privileged mode.
+	Options            string             `yaml:"options"`              // Options specifies additional options for the container.
+	WorkdirParent      string             `yaml:"workdir_parent"`       // WorkdirParent specifies the parent directory for the container's working directory.
+	ValidVolumes       []string           `yaml:"valid_volumes"`        // ValidVolumes specifies the volumes (including bind mounts) can be mounted to containers.
+	DockerHost         string             `yaml:"docker_host"`          // DockerHost specifies the Docker host. It overrides the value specified in environment variable DOCKER_HOST.
+	DockerWaitTimeout  time.Duration      `yaml:"docker_wait_timeout"`  // DockerWaitTimeout specifies how long to wait for the docker daemon on startup.
+	DockerPingInterval time.Duration      `yaml:"docker_ping_interval"` // DockerPingInterval specifies the interval between two pings to the docker daemon while running, 0 means not to ping it.
+	DockerStateFile    string             `yaml:"docker_state_file"`    // DockerStateFile specifies the file to write whether the docker daemon is reachable to.
+	ForcePull          bool               `yaml:"force_pull"`           // Pull docker image(s) even if already present
+	ForceRebuild       bool               `yaml:"force_rebuild"`        // Rebuild docker image(s) even if already present
+	Resources          Resources          `yaml:"resources"`            // Resources represents the default resource limits of job and service containers.
+	ResourceOverrides  []ResourceOverride `yaml:"resource_overrides"`   // ResourceOverrides represent the resource limits of specific labels or repositories, the later ones take precedence.
+	Prepull            Prepull            `yaml:"prepull"`              // Prepull represents the configuration for pulling the images of the docker labels in advance.
+	AllowedImages      []string           `yaml:"allowed_images"`       // AllowedImages specify the glob patterns of the images which job containers, service containers and docker:// steps may use.
+	RequireDigest      bool               `yaml:"require_digest"`       // RequireDigest indicates whether those images must be referenced by digest.
+	ImageLockFile      string             `yaml:"image_lock_file"`      // ImageLockFile specifies the file which pins those images to digests.
+	Registries         []Registry         `yaml:"registries"`           // Registries represent the credentials and mirrors of container registries.
+	ImageRewrites      []ImageRewrite     `yaml:"image_rewrites"`       // ImageRewrites specify the images pulled instead of the ones matching patterns.
+	OrphanCleanup      OrphanCleanup      `yaml:"orphan_cleanup"`       // OrphanCleanup represents the removal of the containers, networks and volumes left behind by tasks.
+}
+
+// Resources represents the resource limits of job and service containers.
+type Resources struct {
+	CPUs       float64  `yaml:"cpus"`        // CPUs specifies the number of CPUs a container may use, like 1.5.
+	Memory     ByteSize `yaml:"memory"`      // Memory specifies the memory a container may use.
+	MemorySwap ByteSize `yaml:"memory_swap"` // MemorySwap specifies the memory plus swap a container may use.
+	PidsLimit  int64    `yaml:"pids_limit"`  // PidsLimit specifies the number of processes a container may run.
+	Disk       ByteSize `yaml:"disk"`        // Disk specifies the size of the writable layer of a container, it requires a storage driver supporting it.
+}
+
+// ResourceOverride represents the resource limits of the jobs matching some labels or repositories.
+type ResourceOverride struct {
+	Labels    []string `yaml:"labels"` // Labels specify the names of the labels, the override applies to jobs running with any of them.
+	Repos     []string `yaml:"repos"`  // Repos specify the repositories like "owner/repo" or "owner/*", the override applies to jobs of any of them.
+	Resources `yaml:",inline"`
+}
+
+// OrphanCleanup represents the configuration for removing the containers, networks and volumes left behind by tasks.
+type OrphanCleanup struct {
+	Enabled  *bool         `yaml:"enabled"`  // Enabled indicates whether they are removed on startup. It is a pointer to distinguish between false and not set. If not set, it will be true.
+	Interval time.Duration `yaml:"interval"` // Interval specifies the interval between two removals after startup, 0 means only on startup.
+	OwnOnly  *bool         `yaml:"own_only"` // OwnOnly indicates whether only the ones created by this runner are removed. It is a pointer to distinguish between false and not set. If not set, it will be true.
+}
+
+// Registry represents the credentials and the mirror of a container registry.
+type Registry struct {
+	Host         string `yaml:"host"`          // Host specifies the domain of the registry, like "docker.io" or "ghcr.io".
+	Username     string `yaml:"username"`      // Username specifies the username to log in to the registry.
+	PasswordFile string `yaml:"password_file"` // PasswordFile specifies the file containing the password or token to log in to the registry.
+	PasswordEnv  string `yaml:"password_env"`  // PasswordEnv specifies the environment variable containing the password or token to log in to the registry.
+	Mirror       string `yaml:"mirror"`        // Mirror specifies the registry, with an optional path, which images of the registry are pulled from instead.
+}
+
+// ImageRewrite represents a rule rewriting the images to pull.
+type ImageRewrite struct {
+	From string `yaml:"from"` // From specifies the pattern of the images, like "docker.io/*", "*" matches any characters.
+	To   string `yaml:"to"`   // To specifies the image pulled instead, like "mirror.local/*", "*" is replaced by what it matched in From.
+}
+
+// Prepull represents the configuration for pulling the images of the docker labels in advance.
+type Prepull struct {
+	Enabled       bool          `yaml:"enabled"`        // Enabled indicates whether the images are pulled on startup.
+	Interval      time.Duration `yaml:"interval"`       // Interval specifies the interval to pull the images again, 0 means only on startup.
+	BeforeDeclare bool          `yaml:"before_declare"` // BeforeDeclare indicates whether to wait for the images to be pulled before declaring the runner and fetching tasks.
 }
 
 // Host represents the configuration for the host.
 type Host struct {
-	WorkdirParent string `yaml:"workdir_parent"` // WorkdirParent specifies the parent directory for the host's working directory.
+	WorkdirParent  string `yaml:"workdir_parent"`  // WorkdirParent specifies the parent directory for the host's working directory.
+	CleanupWorkdir bool   `yaml:"cleanup_workdir"` // CleanupWorkdir indicates whether the working directory of a task is removed after the task.
+}
+
+// Hooks represents the configuration for the host-side job hooks.
+type Hooks struct {
+	PreJob  []string      `yaml:"pre_job"`  // PreJob specifies the executables to run on the host before a task starts. A failing one rejects the task.
+	PostJob []string      `yaml:"post_job"` // PostJob specifies the executables to run on the host after a task ends, even if it was cancelled or timed out.
+	Timeout time.Duration `yaml:"timeout"`  // Timeout specifies the maximum duration of a single hook.
+}
+
+// GCPolicy represents the limits of a directory cleaned by the garbage collection.
+type GCPolicy struct {
+	MaxSize ByteSize      `yaml:"max_size"` // MaxSize specifies the total size the directory may take, 0 means unlimited.
+	MaxAge  time.Duration `yaml:"max_age"`  // MaxAge specifies how long an entry may stay unused before it's removed, 0 means forever.
+	Protect time.Duration `yaml:"protect"`  // Protect specifies how long an entry is considered in use after it has been used.
+}
+
+// GC represents the configuration for the garbage collection of the working and cache directories.
+type GC struct {
+	Enabled  bool          `yaml:"enabled"`  // Enabled indicates whether the daemon collects garbage in the background.
+	Interval time.Duration `yaml:"interval"` // Interval specifies the interval between two collections.
+	Workdir  GCPolicy      `yaml:"workdir"`  // Workdir represents the limits of the host working directory, which contains the action clones.
+	Cache    GCPolicy      `yaml:"cache"`    // Cache represents the limits of the cache server directory.
+	Logs     GCPolicy      `yaml:"logs"`     // Logs represents the limits of the log archive directory.
+	Images   ImageGC       `yaml:"images"`   // Images represents the garbage collection of docker images.
+}
+
+// ImageGC represents the configuration for removing unused docker images.
+type ImageGC struct {
+	Enabled   bool          `yaml:"enabled"`    // Enabled indicates whether the daemon removes unused images in the background.
+	MaxAge    time.Duration `yaml:"max_age"`    // MaxAge specifies how long an image may stay unused before it's removed.
+	MaxSize   ByteSize      `yaml:"max_size"`   // MaxSize specifies the disk space the images may take, the least recently used images are removed first.
+	All       bool          `yaml:"all"`        // All indicates whether images never used by the runner are removed as well.
+	UsageFile string        `yaml:"usage_file"` // UsageFile specifies the file recording when the runner used each image.
 }
 
 // Config represents the overall configuration.
@@ -70,6 +192,8 @@ type Config struct {
 	Cache     Cache     `yaml:"cache"`     // Cache represents the configuration for caching.
 	Container Container `yaml:"container"` // Container represents the configuration for the container.
 	Host      Host      `yaml:"host"`      // Host represents the configuration for the host.
+	Hooks     Hooks     `yaml:"hooks"`     // Hooks represents the configuration for the host-side job hooks.
+	GC        GC        `yaml:"gc"`        // GC represents the configuration for the garbage collection.
 }
 
 // LoadDefault returns the default configuration.
@@ -124,13 +248,63 @@ func LoadDefault(file string) (*Config, error) {
 			cfg.Cache.Dir = filepath.Join(home, ".cache", "actcache")
 		}
 	}
+	if cfg.Container.OrphanCleanup.Enabled == nil {
+		b := true
+		cfg.Container.OrphanCleanup.Enabled = &b
+	}
+	if cfg.Container.OrphanCleanup.OwnOnly == nil {
+		b := true
+		cfg.Container.OrphanCleanup.OwnOnly = &b
+	}
 	if cfg.Container.WorkdirParent == "" {
 		cfg.Container.WorkdirParent = "workspace"
 	}
+	if cfg.Runner.ActionStore == "" {
+		home, _ := os.UserHomeDir()
+		cfg.Runner.ActionStore = filepath.Join(home, ".cache", "actstore")
+	}
 	if cfg.Host.WorkdirParent == "" {
 		home, _ := os.UserHomeDir()
 		cfg.Host.WorkdirParent = filepath.Join(home, ".cache", "act")
 	}
+	if cfg.Hooks.Timeout <= 0 {
+		cfg.Hooks.Timeout = 5 * time.Minute
+	}
+	if cfg.GC.Interval <= 0 {
+		cfg.GC.Interval = time.Hour
+	}
+	if cfg.GC.Workdir.Protect <= 0 {
+		// an action clone may be used by a job as long as the job runs
+		cfg.GC.Workdir.Protect = cfg.Runner.Timeout
+	}
+	if cfg.GC.Images.UsageFile == "" {
+		cfg.GC.Images.UsageFile = ".image_usage.json"
+	}
+	if cfg.GC.Cache.Protect <= 0 {
+		cfg.GC.Cache.Protect = time.Hour
+	}
+	if cfg.GC.Logs.Protect <= 0 {
+		cfg.GC.Logs.Protect = time.Hour
+	}
+	if cfg.Runner.StepSummaryLimit <= 0 {
+		cfg.Runner.StepSummaryLimit = 1 << 20
+	}
+	switch cfg.Runner.OutputSecrets {
+	case "":
+		cfg.Runner.OutputSecrets = "redact"
+	case "redact", "drop", "fail":
+	default:
+		return nil, fmt.Errorf("invalid output_secrets %q, it should be redact, drop or fail", cfg.Runner.OutputSecrets)
+	}
+	if cfg.Runner.Outbox.Dir == "" {
+		cfg.Runner.Outbox.Dir = ".outbox"
+	}
+	if cfg.Runner.Outbox.MaxMemoryRows <= 0 {
+		cfg.Runner.Outbox.MaxMemoryRows = 10000
+	}
+	if cfg.Runner.Outbox.Interval <= 0 {
+		cfg.Runner.Outbox.Interval = time.Minute
+	}
 	if cfg.Runner.FetchTimeout <= 0 {
 		cfg.Runner.FetchTimeout = 5 * time.Second
 	}
@@ -0,0 +1,23 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package docker provides the operations the runner performs on the docker daemon by itself,
+// besides the ones act performs to run jobs.
+package docker
+
+import (
+	"github.com/docker/docker/client"
+)
+
+// NewClient returns a client of the docker daemon at host.
+// If host is empty, the environment variables like DOCKER_HOST are used.
+func NewClient(host string) (*client.Client, error) {
+	opts := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	return client.NewClientWithOpts(opts...)
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// HasNamePrefix reports whether a container or network name has been created by act with the given prefix.
+// Act joins the prefix and the other parts of a name with "_" or "-",
+// so "GITEA-ACTIONS-TASK-1" doesn't match "GITEA-ACTIONS-TASK-12_...".
+func HasNamePrefix(name, prefix string) bool {
+	name = strings.TrimPrefix(name, "/")
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	rest := name[len(prefix):]
+	return rest == "" || rest[0] == '_' || rest[0] == '-'
+}
+
+// WatchOOM calls fn with the name of every container with the given name prefix
+// which is killed because it runs out of memory, until ctx is done.
+func WatchOOM(ctx context.Context, cli client.APIClient, prefix string, fn func(name string)) error {
+	return watchContainers(ctx, cli, prefix, events.ActionOOM, func(msg events.Message) {
+		fn(msg.Actor.Attributes["name"])
+	})
+}
+
+// WatchCreate calls fn with the name and the image of every container with the given name prefix
+// which is created, until ctx is done.
+func WatchCreate(ctx context.Context, cli client.APIClient, prefix string, fn func(name, image string)) error {
+	return watchContainers(ctx, cli, prefix, events.ActionCreate, func(msg events.Message) {
+		fn(msg.Actor.Attributes["name"], msg.Actor.Attributes["image"])
+	})
+}
+
+// watchContainers calls fn with every event of the action of the containers with the given name prefix, until ctx is done.
+func watchContainers(ctx context.Context, cli client.APIClient, prefix string, action events.Action, fn func(msg events.Message)) error {
+	msgs, errs := cli.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", string(action)),
+		),
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		case msg := <-msgs:
+			if HasNamePrefix(msg.Actor.Attributes["name"], prefix) {
+				fn(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	log "github.com/sirupsen/logrus"
+)
+
+// PullImage pulls the image and logs the progress to logger.
+// The progress of every layer is logged at debug level, and a summary at most every progressInterval at info level.
+func PullImage(ctx context.Context, cli client.APIClient, image string, opts types.ImagePullOptions, logger log.FieldLogger) error {
+	const progressInterval = 10 * time.Second
+
+	start := time.Now()
+	logger.Infof("pulling image %s", image)
+	reader, err := cli.ImagePull(ctx, image, opts)
+	if err != nil {
+		return fmt.Errorf("pull image %s: %w", image, err)
+	}
+	defer reader.Close()
+
+	var (
+		layers   = map[string]string{}
+		reported = time.Now()
+		decoder  = json.NewDecoder(reader)
+	)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("pull image %s: %w", image, err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("pull image %s: %w", image, msg.Error)
+		}
+		if msg.ID == "" {
+			logger.Debugf("%s: %s", image, msg.Status)
+			continue
+		}
+		layers[msg.ID] = msg.Status
+		logger.Debugf("%s: %s %s", image, msg.ID, msg.Status)
+		if time.Since(reported) >= progressInterval {
+			reported = time.Now()
+			done := 0
+			for _, status := range layers {
+				if status == "Pull complete" || status == "Already exists" {
+					done++
+				}
+			}
+			logger.Infof("pulling image %s: %d/%d layers done", image, done, len(layers))
+		}
+	}
+
+	logger.Infof("pulled image %s in %s", image, time.Since(start).Round(time.Second))
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// The labels set on the containers, networks and volumes created for tasks,
+// so external tools can map them back to the jobs.
+const (
+	LabelRunnerUUID = "gitea.actions.runner.uuid"
+	LabelRunnerName = "gitea.actions.runner.name"
+	LabelTaskID     = "gitea.actions.task.id"
+	LabelRepository = "gitea.actions.repository"
+	LabelRunID      = "gitea.actions.run.id"
+	LabelJob        = "gitea.actions.job"
+	LabelStartedAt  = "gitea.actions.started_at" // LabelStartedAt is the time the task started, in RFC 3339.
+	LabelService    = "gitea.actions.service"    // LabelService is the ID of the service, it's only set on service containers.
+)
+
+// The kinds of resources created for tasks, in the order they should be removed.
+const (
+	KindContainer = "container"
+	KindNetwork   = "network"
+	KindVolume    = "volume"
+)
+
+// Resource is a container, network or volume created for a task.
+type Resource struct {
+	Kind   string
+	ID     string
+	Name   string
+	TaskID int64
+	Labels map[string]string
+}
+
+// TaskID returns the id of the task which a resource named with the prefix, like "GITEA-ACTIONS-TASK-", belongs to.
+func TaskID(name, prefix string) (int64, bool) {
+	name = strings.TrimPrefix(name, "/")
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	rest := name[len(prefix):]
+	end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	if end == -1 {
+		end = len(rest)
+	}
+	if end == 0 || (end < len(rest) && rest[end] != '_' && rest[end] != '-') {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest[:end], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// ListTaskResources lists the containers, networks and volumes whose names start with the prefix followed by a task id,
+// like "GITEA-ACTIONS-TASK-12_...". If labels isn't empty, only the resources with all the labels are listed.
+func ListTaskResources(ctx context.Context, cli client.APIClient, prefix string, labels map[string]string) ([]Resource, error) {
+	args := filters.NewArgs(filters.Arg("name", prefix))
+	for k, v := range labels {
+		args.Add("label", k+"="+v)
+	}
+
+	var resources []Resource
+	add := func(kind, id, name string, labels map[string]string) {
+		if taskID, ok := TaskID(name, prefix); ok {
+			resources = append(resources, Resource{
+				Kind:   kind,
+				ID:     id,
+				Name:   strings.TrimPrefix(name, "/"),
+				TaskID: taskID,
+				Labels: labels,
+			})
+		}
+	}
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	for _, c := range containers {
+		for _, name := range c.Names {
+			if _, ok := TaskID(name, prefix); ok {
+				add(KindContainer, c.ID, name, c.Labels)
+				break
+			}
+		}
+	}
+
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("list networks: %w", err)
+	}
+	for _, n := range networks {
+		add(KindNetwork, n.ID, n.Name, n.Labels)
+	}
+
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if v != nil {
+			add(KindVolume, v.Name, v.Name, v.Labels)
+		}
+	}
+	return resources, nil
+}
+
+// RemoveResource removes a container, along with its anonymous volumes, a network or a volume.
+func RemoveResource(ctx context.Context, cli client.APIClient, r Resource) error {
+	switch r.Kind {
+	case KindContainer:
+		return cli.ContainerRemove(ctx, r.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	case KindNetwork:
+		return cli.NetworkRemove(ctx, r.ID)
+	case KindVolume:
+		return cli.VolumeRemove(ctx, r.ID, true)
+	}
+	return fmt.Errorf("unknown kind of resource: %s", r.Kind)
+}
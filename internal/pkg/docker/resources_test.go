@@ -0,0 +1,33 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   int64
+		ok   bool
+	}{
+		{name: "/GITEA-ACTIONS-TASK-12_WORKFLOW-build_JOB-test", id: 12, ok: true},
+		{name: "GITEA-ACTIONS-TASK-12_WORKFLOW-build_JOB-test-test-network", id: 12, ok: true},
+		{name: "GITEA-ACTIONS-TASK-7", id: 7, ok: true},
+		{name: "GITEA-ACTIONS-TASK-7-env", id: 7, ok: true},
+		{name: "GITEA-ACTIONS-TASK-", ok: false},
+		{name: "GITEA-ACTIONS-TASK-12abc", ok: false},
+		{name: "OTHER-12", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := TaskID(tt.name, "GITEA-ACTIONS-TASK-")
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.id, id)
+		})
+	}
+}
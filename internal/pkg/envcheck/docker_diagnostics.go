@@ -0,0 +1,69 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/client"
+
+	"gitea.com/gitea/act_runner/internal/pkg/docker"
+)
+
+// MinDockerAPIVersion is the oldest API version of the docker daemon which the runner is tested with.
+const MinDockerAPIVersion = "1.41"
+
+// CheckDockerSocket checks whether the docker daemon listening at host is reachable.
+// It's used for the sockets found while discovering the docker host, they are not necessarily used by the runner,
+// so an unreachable one is only a warning.
+func CheckDockerSocket(ctx context.Context, host string) Result {
+	check := "docker socket " + host
+	if err := CheckIfDockerRunning(ctx, host); err != nil {
+		return Warn(check, "found but not reachable: %v", err)
+	}
+	return Pass(check, "reachable")
+}
+
+// CheckDocker checks the docker daemon at host which the runner uses: whether it's reachable,
+// whether its API version is supported, and whether the images exist locally.
+func CheckDocker(ctx context.Context, host string, images []string) []Result {
+	cli, err := docker.NewClient(host)
+	if err != nil {
+		return []Result{Fail("docker host", "failed to create a docker client for %s: %v", host, err)}
+	}
+	defer cli.Close()
+
+	if _, err := cli.Ping(ctx); err != nil {
+		return []Result{Fail("docker host", "cannot ping the docker daemon at %s: %v", host, err)}
+	}
+	results := []Result{Pass("docker host", "using %s", host)}
+
+	v, err := cli.ServerVersion(ctx)
+	switch {
+	case err != nil:
+		results = append(results, Fail("docker version", "failed to get the version of the docker daemon: %v", err))
+	case versions.LessThan(v.APIVersion, MinDockerAPIVersion):
+		results = append(results, Warn("docker version", "%s %s with API version %s, which is older than %s, some features may not work",
+			v.Platform.Name, v.Version, v.APIVersion, MinDockerAPIVersion))
+	default:
+		results = append(results, Pass("docker version", "%s %s with API version %s", v.Platform.Name, v.Version, v.APIVersion))
+	}
+
+	for _, image := range images {
+		results = append(results, checkImage(ctx, cli, image))
+	}
+	return results
+}
+
+func checkImage(ctx context.Context, cli *client.Client, image string) Result {
+	check := "image " + image
+	if _, _, err := cli.ImageInspectWithRaw(ctx, image); err != nil {
+		if client.IsErrNotFound(err) {
+			return Warn(check, "not present locally, it will be pulled when a job uses it")
+		}
+		return Fail(check, "failed to inspect: %v", err)
+	}
+	return Pass(check, "present locally")
+}
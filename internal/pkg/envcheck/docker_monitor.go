@@ -0,0 +1,126 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DockerState describes whether the docker daemon is reachable.
+type DockerState struct {
+	Available bool      `json:"available"`
+	Since     time.Time `json:"since"`           // Since is when the state changed last.
+	Error     string    `json:"error,omitempty"` // Error is why the docker daemon isn't reachable.
+}
+
+// DockerMonitor pings the docker daemon periodically and tracks whether it's reachable.
+type DockerMonitor struct {
+	host      string
+	interval  time.Duration
+	stateFile string // stateFile is where the state is written to on every change, empty means it's not written.
+
+	mu        sync.Mutex
+	state     DockerState
+	available chan struct{} // available is closed while the docker daemon is reachable
+}
+
+// NewDockerMonitor returns a monitor of the docker daemon at host, which is assumed to be reachable at first.
+func NewDockerMonitor(host string, interval time.Duration, stateFile string) *DockerMonitor {
+	m := &DockerMonitor{
+		host:      host,
+		interval:  interval,
+		stateFile: stateFile,
+		state: DockerState{
+			Available: true,
+			Since:     time.Now(),
+		},
+		available: make(chan struct{}),
+	}
+	close(m.available)
+	m.writeState(m.state)
+	return m
+}
+
+// Run pings the docker daemon every interval until ctx is done.
+func (m *DockerMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, m.interval)
+		err := CheckIfDockerRunning(pingCtx, m.host)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		m.update(err)
+	}
+}
+
+func (m *DockerMonitor) update(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	available := err == nil
+	if available == m.state.Available {
+		return
+	}
+	m.state = DockerState{
+		Available: available,
+		Since:     time.Now(),
+	}
+	if available {
+		log.Info("the docker daemon is reachable again, resume fetching tasks")
+		close(m.available)
+	} else {
+		m.state.Error = err.Error()
+		log.WithError(err).Error("the docker daemon is unreachable, stop fetching tasks until it comes back")
+		m.available = make(chan struct{})
+	}
+	m.writeState(m.state)
+}
+
+func (m *DockerMonitor) writeState(state DockerState) {
+	if m.stateFile == "" {
+		return
+	}
+	content, err := json.Marshal(state)
+	if err == nil {
+		err = os.WriteFile(m.stateFile, append(content, '\n'), 0o644)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("failed to write the state of the docker daemon to %s", m.stateFile)
+	}
+}
+
+// State returns the current state.
+func (m *DockerMonitor) State() DockerState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Wait blocks until the docker daemon is reachable or ctx is done.
+func (m *DockerMonitor) Wait(ctx context.Context) error {
+	m.mu.Lock()
+	available := m.available
+	m.mu.Unlock()
+
+	select {
+	case <-available:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerMonitor(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "docker.json")
+	m := NewDockerMonitor("unix:///nonexistent.sock", time.Minute, stateFile)
+	require.NoError(t, m.Wait(context.Background()))
+
+	readState := func() DockerState {
+		content, err := os.ReadFile(stateFile)
+		require.NoError(t, err)
+		var state DockerState
+		require.NoError(t, json.Unmarshal(content, &state))
+		return state
+	}
+	assert.True(t, readState().Available)
+
+	m.update(errors.New("connection refused"))
+	assert.False(t, m.State().Available)
+	state := readState()
+	assert.False(t, state.Available)
+	assert.Equal(t, "connection refused", state.Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Wait(ctx), context.DeadlineExceeded)
+
+	waited := make(chan error)
+	go func() {
+		waited <- m.Wait(context.Background())
+	}()
+	m.update(nil)
+	require.NoError(t, <-waited)
+	assert.True(t, readState().Available)
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn" // StatusWarn means the runner works, but maybe not as expected.
+	StatusFail Status = "fail" // StatusFail means the runner doesn't work, or can't run some jobs.
+)
+
+// Result is the outcome of a check, with a message describing what has been found.
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+func Pass(check, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func Warn(check, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func Fail(check, format string, args ...any) Result {
+	return Result{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, args...)}
+}
+
+// Report collects the results of checks in order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+func (r *Report) Add(results ...Result) {
+	r.Results = append(r.Results, results...)
+}
+
+// Count returns how many results have the given status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes the results as text, one result per line.
+func (r *Report) Print(w io.Writer) {
+	width := 0
+	for _, result := range r.Results {
+		width = max(width, len(result.Check))
+	}
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "[%s] %-*s  %s\n", strings.ToUpper(string(result.Status)), width, result.Check, result.Message)
+	}
+	fmt.Fprintf(w, "%d passed, %d warnings, %d failed\n", r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail))
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	report := &Report{}
+	report.Add(
+		Pass("config", "loaded %s", "config.yaml"),
+		Warn("labels", "no labels configured"),
+		Fail("docker host", "cannot ping"),
+	)
+	assert.Equal(t, 1, report.Count(StatusFail))
+
+	sb := &strings.Builder{}
+	report.Print(sb)
+	assert.Equal(t, `[PASS] config       loaded config.yaml
+[WARN] labels       no labels configured
+[FAIL] docker host  cannot ping
+1 passed, 1 warnings, 1 failed
+`, sb.String())
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/nektos/act/pkg/common"
+)
+
+const (
+	// MinFreeSpace is the free disk space below which jobs will likely fail.
+	MinFreeSpace = 1 << 30
+	// LowFreeSpace is the free disk space below which jobs may fail, like when pulling large images.
+	LowFreeSpace = 5 << 30
+
+	// MaxClockSkew is the clock skew beyond which tokens issued by the Gitea instance may be rejected.
+	MaxClockSkew = 5 * time.Minute
+	// LowClockSkew is the clock skew beyond which the clock should be synchronized.
+	LowClockSkew = 30 * time.Second
+)
+
+// CheckSecretFile checks whether file exists and isn't readable by other users, since it contains secrets.
+func CheckSecretFile(check, file string) Result {
+	stat, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Fail(check, "%s doesn't exist", file)
+	} else if err != nil {
+		return Fail(check, "failed to stat %s: %v", file, err)
+	}
+	// the permission bits don't describe who can read the file on Windows
+	if mode := stat.Mode().Perm(); runtime.GOOS != "windows" && mode&0o077 != 0 {
+		return Warn(check, "%s is accessible by other users (%v), it should be chmod 600 since it contains secrets", file, mode)
+	}
+	return Pass(check, "%s exists", file)
+}
+
+// CheckDiskSpace checks the free disk space of the file system which dir is on.
+// The dir could be created later, then its nearest existing parent is checked.
+func CheckDiskSpace(check, dir string) Result {
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return Fail(check, "invalid directory %s: %v", dir, err)
+	}
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	free, err := diskFree(path)
+	if err != nil {
+		return Fail(check, "failed to get the free disk space of %s: %v", path, err)
+	}
+	return checkFreeSpace(check, dir, free)
+}
+
+func checkFreeSpace(check, dir string, free uint64) Result {
+	switch {
+	case free < MinFreeSpace:
+		return Fail(check, "only %s free for %s", units.BytesSize(float64(free)), dir)
+	case free < LowFreeSpace:
+		return Warn(check, "only %s free for %s", units.BytesSize(float64(free)), dir)
+	default:
+		return Pass(check, "%s free for %s", units.BytesSize(float64(free)), dir)
+	}
+}
+
+// CheckClockSkew compares the local time with the time of the Gitea instance.
+func CheckClockSkew(local, remote time.Time) Result {
+	skew := local.Sub(remote)
+	if skew < 0 {
+		skew = -skew
+	}
+	// the time of the instance comes from the Date header, which is in seconds
+	skew = skew.Truncate(time.Second)
+	switch {
+	case skew > MaxClockSkew:
+		return Fail("clock skew", "the local clock differs from the Gitea instance by %v", skew)
+	case skew > LowClockSkew:
+		return Warn("clock skew", "the local clock differs from the Gitea instance by %v", skew)
+	default:
+		return Pass("clock skew", "the local clock differs from the Gitea instance by %v", skew)
+	}
+}
+
+// CheckCacheServer checks whether the cache server could listen on host and port, like the runner does.
+// An empty host means the outbound IP, which the job containers use to reach the cache server,
+// and a zero port means a random one.
+// The address is fine if it's in use by a cache server, which is the one of the runner running on the host.
+func CheckCacheServer(host string, port uint16) []Result {
+	var results []Result
+	if host == "" {
+		ip := common.GetOutboundIP()
+		if ip == nil {
+			return []Result{Fail("cache server host", "failed to detect the outbound IP, please set cache.host")}
+		}
+		host = ip.String()
+		if ip.IsLoopback() {
+			results = append(results, Warn("cache server host", "the outbound IP is %s, job containers can't reach it", host))
+		} else {
+			results = append(results, Pass("cache server host", "the outbound IP is %s", host))
+		}
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		if isCacheServer(addr) {
+			return append(results, Pass("cache server bind", "a cache server, likely the one of the running runner, listens on %s", addr))
+		}
+		return append(results, Fail("cache server bind", "cannot listen on %s: %v", addr, err))
+	}
+	_ = listener.Close()
+	return append(results, Pass("cache server bind", "can listen on %s", addr))
+}
+
+// isCacheServer returns whether a cache server listens on addr, it finds a cache which doesn't exist.
+func isCacheServer(addr string) bool {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get("http://" + addr + "/_apis/artifactcache/cache?keys=envcheck")
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK
+}
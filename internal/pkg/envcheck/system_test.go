@@ -0,0 +1,72 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package envcheck
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".runner")
+	assert.Equal(t, StatusFail, CheckSecretFile("registration", file).Status)
+
+	assert.NoError(t, os.WriteFile(file, []byte("{}"), 0o600))
+	assert.Equal(t, StatusPass, CheckSecretFile("registration", file).Status)
+
+	if runtime.GOOS != "windows" {
+		assert.NoError(t, os.Chmod(file, 0o644))
+		assert.Equal(t, StatusWarn, CheckSecretFile("registration", file).Status)
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	// a directory which doesn't exist yet is checked by its parent
+	result := CheckDiskSpace("disk space", filepath.Join(t.TempDir(), "not", "created"))
+	assert.NotEqual(t, "", result.Message)
+
+	assert.Equal(t, StatusFail, checkFreeSpace("disk space", "/data", 100<<20).Status)
+	assert.Equal(t, StatusWarn, checkFreeSpace("disk space", "/data", 2<<30).Status)
+	assert.Equal(t, StatusPass, checkFreeSpace("disk space", "/data", 50<<30).Status)
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, StatusPass, CheckClockSkew(now, now.Add(-10*time.Second)).Status)
+	assert.Equal(t, StatusWarn, CheckClockSkew(now, now.Add(time.Minute)).Status)
+	assert.Equal(t, StatusFail, CheckClockSkew(now, now.Add(-time.Hour)).Status)
+}
+
+func TestCheckCacheServer(t *testing.T) {
+	results := CheckCacheServer("127.0.0.1", 0)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, StatusPass, results[0].Status)
+	}
+
+	// the address in use by the cache server of a running runner is fine, unlike the one in use by another program
+	for status, want := range map[int]Status{http.StatusNoContent: StatusPass, http.StatusNotFound: StatusFail} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/_apis/artifactcache/cache" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+		addr := server.Listener.Addr().(*net.TCPAddr)
+		results := CheckCacheServer("127.0.0.1", uint16(addr.Port))
+		server.Close()
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, want, results[0].Status, results[0].Message)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package gc removes unused entries from the directories the runner writes to.
+package gc
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Policy describes when entries of a directory should be removed.
+type Policy struct {
+	MaxSize int64         // MaxSize is the total size the entries may take, 0 means unlimited. The least recently used entries are removed first.
+	MaxAge  time.Duration // MaxAge is how long an entry may stay unused, 0 means forever.
+	Protect time.Duration // Protect is how long an entry is considered in use after it has been used, it will never be removed within this window.
+	// Lock locks an entry before it's removed, and returns the function to unlock it.
+	// It returns false if the entry is in use, then the entry is kept. Nil means no entry needs to be locked.
+	Lock func(path string) (unlock func(), ok bool)
+}
+
+// Entry is a file or directory which is removed as a whole.
+type Entry struct {
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// Result describes what has been, or would be in a dry run, removed from a directory.
+type Result struct {
+	Dir     string
+	Removed []Entry
+	Freed   int64
+	Kept    int
+	Size    int64 // Size is the total size of the kept entries.
+}
+
+// Scan lists the entries at the given depth below dir.
+// Depth 1 means the direct children of dir. A missing dir has no entries.
+func Scan(dir string, depth int) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if level := strings.Count(rel, string(filepath.Separator)) + 1; level < depth {
+			return nil
+		}
+		size, lastUsed, err := usage(path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{
+			Path:     path,
+			Size:     size,
+			LastUsed: lastUsed,
+		})
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// usage returns the total size of path and the latest modification time of anything in it.
+func usage(path string) (int64, time.Time, error) {
+	var (
+		size     int64
+		lastUsed time.Time
+	)
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		if t := info.ModTime(); t.After(lastUsed) {
+			lastUsed = t
+		}
+		return nil
+	})
+	return size, lastUsed, err
+}
+
+// Select returns the entries which should be removed according to the policy.
+// Entries unused for longer than MaxAge go first, then the least recently used ones until the rest fits in MaxSize.
+func Select(entries []Entry, policy Policy, now time.Time) (remove, keep []Entry) {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastUsed.Before(sorted[j].LastUsed)
+	})
+
+	var total int64
+	for _, e := range sorted {
+		total += e.Size
+	}
+
+	for _, e := range sorted {
+		unused := now.Sub(e.LastUsed)
+		switch {
+		case unused < policy.Protect:
+			keep = append(keep, e)
+		case policy.MaxAge > 0 && unused > policy.MaxAge,
+			policy.MaxSize > 0 && total > policy.MaxSize:
+			remove = append(remove, e)
+			total -= e.Size
+		default:
+			keep = append(keep, e)
+		}
+	}
+	return remove, keep
+}
+
+// Collect removes the entries at the given depth below dir according to the policy.
+// Nothing is removed if dryRun is true, but the result still describes what would be.
+func Collect(dir string, depth int, policy Policy, dryRun bool) (*Result, error) {
+	entries, err := Scan(dir, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	remove, keep := Select(entries, policy, time.Now())
+	result := &Result{
+		Dir:  dir,
+		Kept: len(keep),
+	}
+	for _, e := range keep {
+		result.Size += e.Size
+	}
+	for _, e := range remove {
+		removed, err := removeEntry(e, policy.Lock, dryRun)
+		if err != nil {
+			return result, err
+		}
+		if !removed {
+			result.Kept++
+			result.Size += e.Size
+			continue
+		}
+		result.Removed = append(result.Removed, e)
+		result.Freed += e.Size
+	}
+	return result, nil
+}
+
+// removeEntry removes an entry while it's locked, it returns false if the entry is in use.
+func removeEntry(e Entry, lock func(string) (func(), bool), dryRun bool) (bool, error) {
+	if lock != nil {
+		unlock, ok := lock(e.Path)
+		if !ok {
+			return false, nil
+		}
+		defer unlock()
+	}
+	if dryRun {
+		return true, nil
+	}
+	return true, os.RemoveAll(e.Path)
+}
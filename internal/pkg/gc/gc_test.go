@@ -0,0 +1,106 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package gc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Path: "new", Size: 10, LastUsed: now.Add(-time.Minute)},
+		{Path: "old", Size: 10, LastUsed: now.Add(-48 * time.Hour)},
+		{Path: "mid", Size: 10, LastUsed: now.Add(-2 * time.Hour)},
+		{Path: "recent", Size: 10, LastUsed: now.Add(-90 * time.Minute)},
+	}
+	paths := func(entries []Entry) []string {
+		var ret []string
+		for _, e := range entries {
+			ret = append(ret, e.Path)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		remove []string
+	}{
+		{"no limits", Policy{}, nil},
+		{"max age", Policy{MaxAge: 24 * time.Hour}, []string{"old"}},
+		{"max size", Policy{MaxSize: 25}, []string{"old", "mid"}},
+		{"protected", Policy{MaxSize: 5, Protect: 100 * time.Minute}, []string{"old", "mid"}},
+		{"max age and size", Policy{MaxAge: time.Hour, MaxSize: 100}, []string{"old", "mid", "recent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remove, keep := Select(entries, tt.policy, now)
+			assert.Equal(t, tt.remove, paths(remove))
+			assert.Len(t, keep, len(entries)-len(tt.remove))
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"a/1", "a/2", "b/3"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+	}
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a/1"), old, old))
+
+	result, err := Collect(dir, 2, Policy{MaxAge: 24 * time.Hour}, true)
+	require.NoError(t, err)
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, filepath.Join(dir, "a/1"), result.Removed[0].Path)
+	assert.FileExists(t, filepath.Join(dir, "a/1"))
+
+	result, err = Collect(dir, 2, Policy{MaxAge: 24 * time.Hour}, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Freed)
+	assert.Equal(t, 2, result.Kept)
+	assert.NoFileExists(t, filepath.Join(dir, "a/1"))
+
+	result, err = Collect(filepath.Join(dir, "missing"), 1, Policy{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+}
+
+func TestCollect_Lock(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"used", "unused"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	var unlocked []string
+	policy := Policy{
+		MaxAge: 24 * time.Hour,
+		Lock: func(path string) (func(), bool) {
+			if filepath.Base(path) == "used" {
+				return nil, false
+			}
+			return func() { unlocked = append(unlocked, filepath.Base(path)) }, true
+		},
+	}
+	result, err := Collect(dir, 1, policy, false)
+	require.NoError(t, err)
+	require.Len(t, result.Removed, 1)
+	assert.Equal(t, filepath.Join(dir, "unused"), result.Removed[0].Path)
+	assert.Equal(t, 1, result.Kept)
+	assert.Equal(t, int64(4), result.Size)
+	assert.Equal(t, []string{"unused"}, unlocked)
+	assert.FileExists(t, filepath.Join(dir, "used"))
+}
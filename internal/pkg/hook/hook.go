@@ -0,0 +1,87 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package hook runs host-side executables before and after a task.
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	StagePreJob  = "pre_job"
+	StagePostJob = "post_job"
+)
+
+// Metadata describes the task a hook is invoked for.
+// It is written as JSON to the stdin of the hook, and exposed as environment variables.
+type Metadata struct {
+	Stage      string `json:"stage"`
+	TaskID     int64  `json:"task_id"`
+	RunID      string `json:"run_id"`
+	Repository string `json:"repository"`
+	Job        string `json:"job"`
+	Event      string `json:"event"`
+	Runner     string `json:"runner"`
+	Result     string `json:"result,omitempty"` // Result is only set for post-job hooks.
+}
+
+func (m *Metadata) environ() []string {
+	return []string{
+		"GITEA_HOOK_STAGE=" + m.Stage,
+		"GITEA_TASK_ID=" + strconv.FormatInt(m.TaskID, 10),
+		"GITEA_TASK_RUN_ID=" + m.RunID,
+		"GITEA_TASK_REPOSITORY=" + m.Repository,
+		"GITEA_TASK_JOB=" + m.Job,
+		"GITEA_TASK_EVENT=" + m.Event,
+		"GITEA_TASK_RUNNER=" + m.Runner,
+		"GITEA_TASK_RESULT=" + m.Result,
+	}
+}
+
+// Run executes the hook at path and calls logf for every line it writes to stdout or stderr.
+// A non-zero exit code is reported as an error.
+func Run(ctx context.Context, path string, meta *Metadata, logf func(format string, a ...interface{})) error {
+	payload, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), meta.environ()...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	// the children of the hook could keep its output open after it has exited or has been killed
+	cmd.WaitDelay = time.Second
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			logf("%s", scanner.Text())
+		}
+		// drain the pipe so the hook never blocks on a long line
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	err = cmd.Run()
+	_ = pw.Close()
+	<-done
+
+	if err != nil {
+		return fmt.Errorf("%s hook %q: %w", meta.Stage, path, err)
+	}
+	return nil
+}
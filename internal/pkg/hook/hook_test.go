@@ -0,0 +1,80 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package hook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHook(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts in the tests")
+	}
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+func run(ctx context.Context, t *testing.T, path string, meta *Metadata) ([]string, error) {
+	t.Helper()
+	var lines []string
+	err := Run(ctx, path, meta, func(format string, a ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	})
+	return lines, err
+}
+
+func TestRun(t *testing.T) {
+	path := writeHook(t, `echo "$GITEA_HOOK_STAGE $GITEA_TASK_ID $GITEA_TASK_REPOSITORY $GITEA_TASK_RESULT"
+echo "to stderr" >&2
+cat
+`)
+	lines, err := run(context.Background(), t, path, &Metadata{
+		Stage:      StagePostJob,
+		TaskID:     42,
+		Repository: "owner/repo",
+		Result:     "success",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"post_job 42 owner/repo success",
+		"to stderr",
+		`{"stage":"post_job","task_id":42,"run_id":"","repository":"owner/repo","job":"","event":"","runner":"","result":"success"}`,
+	}, lines)
+}
+
+func TestRun_Failure(t *testing.T) {
+	path := writeHook(t, "echo denied\nexit 3\n")
+	lines, err := run(context.Background(), t, path, &Metadata{Stage: StagePreJob})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Contains(t, err.Error(), "pre_job hook")
+	assert.Equal(t, []string{"denied"}, lines)
+
+	_, err = run(context.Background(), t, filepath.Join(t.TempDir(), "missing"), &Metadata{Stage: StagePreJob})
+	assert.Error(t, err)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	// the child keeps the output open after the hook has been killed
+	path := writeHook(t, "echo started\nsleep 30\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	lines, err := run(ctx, t, path, &Metadata{Stage: StagePreJob})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, []string{"started"}, lines)
+}
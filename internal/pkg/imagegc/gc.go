@@ -0,0 +1,240 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package imagegc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// actionImagePattern matches the tags of the images act builds for docker actions, like "act-owner-repo-dockeraction:latest".
+var actionImagePattern = regexp.MustCompile(`^act-.*-dockeraction:`)
+
+// isImageID returns whether an image of the usage is the ID of an image adopted by the runner, rather than a name.
+func isImageID(image string) bool {
+	return strings.HasPrefix(image, "sha256:")
+}
+
+// Policy describes which images should be removed.
+type Policy struct {
+	MaxAge  time.Duration // MaxAge is how long an image may stay unused, 0 means forever.
+	MaxSize int64         // MaxSize is the disk space the images may take, 0 means unlimited. The least recently used images are removed first.
+	Keep    []string      // Keep are the images never removed, like the ones of the labels.
+	// All indicates whether every image on the docker host is collected.
+	// Otherwise only the images used by the runner, the images built for docker actions,
+	// and the dangling images which were tagged like them, are.
+	All bool
+}
+
+// Image is an image which could be removed.
+type Image struct {
+	ID       string
+	Name     string   // Name is the first tag of the image, or its ID if it's dangling.
+	Tags     []string // Tags are the tags of the image, it has none if it's dangling.
+	Size     int64    // Size is the disk space freed by removing the image, which excludes the layers shared with other images.
+	LastUsed time.Time
+	Dangling bool
+	Reason   string // Reason is why the image is removed.
+}
+
+// Result describes what has been, or would be in a dry run, removed.
+type Result struct {
+	Removed []Image
+	Freed   int64
+	Kept    int
+	Size    int64 // Size is the disk space taken by the images after the removal.
+}
+
+// Collect removes the images according to the policy.
+// Images used by containers, held by running tasks or kept by the policy are never removed.
+// Nothing is removed if dryRun is true, but the result still describes what would be.
+func Collect(ctx context.Context, cli client.APIClient, tracker *Tracker, policy Policy, dryRun bool) (*Result, error) {
+	du, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.ImageObject}})
+	if err != nil {
+		return nil, fmt.Errorf("get disk usage: %w", err)
+	}
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	usage, err := tracker.Usage()
+	if err != nil {
+		return nil, fmt.Errorf("load image usage: %w", err)
+	}
+
+	inUse := map[string]bool{}
+	for _, c := range containers {
+		inUse[c.ImageID] = true
+	}
+	keep := map[string]bool{}
+	for _, image := range policy.Keep {
+		keep[Normalize(image)] = true
+	}
+
+	var (
+		candidates []Image
+		adopt      []string
+		kept       int
+	)
+	present := map[string]bool{}
+	for _, summary := range du.Images {
+		if summary == nil {
+			continue
+		}
+		present[summary.ID] = true
+		img := Image{
+			ID:       summary.ID,
+			Name:     summary.ID,
+			Size:     summary.Size,
+			LastUsed: time.Unix(summary.Created, 0),
+		}
+		if summary.SharedSize > 0 {
+			img.Size -= summary.SharedSize
+		}
+
+		var tags []string
+		for _, tag := range summary.RepoTags {
+			if tag != "<none>:<none>" {
+				tags = append(tags, tag)
+			}
+		}
+		img.Tags = tags
+		img.Dangling = len(tags) == 0
+		if !img.Dangling {
+			img.Name = tags[0]
+		}
+
+		protected := inUse[summary.ID]
+		// an image which has become dangling is only collected if the runner has adopted it before,
+		// the other dangling images could belong to other tools
+		_, adopted := usage[summary.ID]
+		managed := policy.All || (img.Dangling && adopted)
+		tracked := false
+		for _, tag := range tags {
+			name := Normalize(tag)
+			if keep[name] || keep[tag] || tracker.Held(tag) {
+				protected = true
+			}
+			if actionImagePattern.MatchString(tag) {
+				managed = true
+			}
+			if t, ok := usage[name]; ok {
+				managed, tracked = true, true
+				if t.After(img.LastUsed) {
+					img.LastUsed = t
+				}
+			}
+		}
+		if managed && !img.Dangling && !adopted {
+			adopt = append(adopt, summary.ID)
+		}
+		if protected || !managed {
+			kept++
+			continue
+		}
+		if !tracked && !img.Dangling {
+			// an image never used by the runner was pulled or built when it was tagged, which could be long after it was created
+			if inspect, _, err := cli.ImageInspectWithRaw(ctx, summary.ID); err == nil && inspect.Metadata.LastTagTime.After(img.LastUsed) {
+				img.LastUsed = inspect.Metadata.LastTagTime
+			}
+		}
+		candidates = append(candidates, img)
+	}
+
+	if !dryRun {
+		var gone []string
+		for image := range usage {
+			if isImageID(image) && !present[image] {
+				gone = append(gone, image)
+			}
+		}
+		_ = tracker.Forget(gone...)
+		_ = tracker.Adopt(adopt...)
+	}
+
+	remove, keepCount := selectImages(candidates, policy, du.LayersSize, time.Now())
+	result := &Result{
+		Kept: kept + keepCount,
+		Size: du.LayersSize,
+	}
+	var errs []error
+	for _, img := range remove {
+		if !dryRun {
+			removed, err := removeImage(ctx, cli, img)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("remove image %s: %w", img.Name, err))
+			}
+			if !removed {
+				result.Kept++
+				continue
+			}
+			_ = tracker.Forget(append(img.Tags, img.ID)...)
+		}
+		result.Removed = append(result.Removed, img)
+		result.Freed += img.Size
+		result.Size -= img.Size
+	}
+	return result, errors.Join(errs...)
+}
+
+// removeImage removes an image without forcing it, it returns false if the image is kept because a container uses it,
+// which could have been created since the containers were listed.
+// An image with several tags is removed by removing every tag, since removing it by its ID would have to be forced.
+func removeImage(ctx context.Context, cli client.APIClient, img Image) (bool, error) {
+	refs := img.Tags
+	if len(refs) == 0 {
+		refs = []string{img.ID}
+	}
+	for _, ref := range refs {
+		if _, err := cli.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+			if errdefs.IsConflict(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// selectImages returns the images which should be removed according to the policy, and the number of the kept ones.
+// Dangling images go first, then the ones unused for longer than MaxAge,
+// then the least recently used ones until the total size fits in MaxSize.
+func selectImages(images []Image, policy Policy, total int64, now time.Time) (remove []Image, kept int) {
+	sorted := make([]Image, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Dangling != sorted[j].Dangling {
+			return sorted[i].Dangling
+		}
+		return sorted[i].LastUsed.Before(sorted[j].LastUsed)
+	})
+
+	for _, img := range sorted {
+		unused := now.Sub(img.LastUsed)
+		switch {
+		case img.Dangling:
+			img.Reason = "dangling"
+		case policy.MaxAge > 0 && unused > policy.MaxAge:
+			img.Reason = fmt.Sprintf("unused for %s", unused.Round(time.Hour))
+		case policy.MaxSize > 0 && total > policy.MaxSize:
+			img.Reason = "images exceed the max size"
+		default:
+			kept++
+			continue
+		}
+		remove = append(remove, img)
+		total -= img.Size
+	}
+	return remove, kept
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package imagegc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(images []Image) []string {
+	var ret []string
+	for _, img := range images {
+		ret = append(ret, img.Name)
+	}
+	return ret
+}
+
+func TestSelectImages(t *testing.T) {
+	now := time.Now()
+	images := []Image{
+		{Name: "new", Size: 100, LastUsed: now.Add(-time.Hour)},
+		{Name: "old", Size: 100, LastUsed: now.Add(-10 * 24 * time.Hour)},
+		{Name: "mid", Size: 100, LastUsed: now.Add(-2 * 24 * time.Hour)},
+		{Name: "sha256:dangling", Size: 10, LastUsed: now, Dangling: true},
+	}
+
+	remove, kept := selectImages(images, Policy{}, 310, now)
+	assert.Equal(t, []string{"sha256:dangling"}, names(remove))
+	assert.Equal(t, 3, kept)
+
+	remove, kept = selectImages(images, Policy{MaxAge: 7 * 24 * time.Hour}, 310, now)
+	assert.Equal(t, []string{"sha256:dangling", "old"}, names(remove))
+	assert.Equal(t, 2, kept)
+
+	remove, kept = selectImages(images, Policy{MaxSize: 150}, 310, now)
+	assert.Equal(t, []string{"sha256:dangling", "old", "mid"}, names(remove))
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, "images exceed the max size", remove[2].Reason)
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "usage.json"))
+
+	release := tracker.Hold("node:18", "ghcr.io/owner/app:1")
+	assert.True(t, tracker.Held("docker.io/library/node:18"))
+	assert.True(t, tracker.Held("ghcr.io/owner/app:1"))
+	assert.False(t, tracker.Held("node:20"))
+
+	again := tracker.Hold("node:18")
+	release()
+	release()
+	assert.True(t, tracker.Held("node:18"))
+	assert.False(t, tracker.Held("ghcr.io/owner/app:1"))
+	again()
+	assert.False(t, tracker.Held("node:18"))
+
+	usage, err := tracker.Usage()
+	require.NoError(t, err)
+	assert.Contains(t, usage, "docker.io/library/node:18")
+	assert.Contains(t, usage, "ghcr.io/owner/app:1")
+
+	require.NoError(t, tracker.Forget("node:18"))
+	usage, err = tracker.Usage()
+	require.NoError(t, err)
+	assert.NotContains(t, usage, "docker.io/library/node:18")
+}
+
+// fakeClient is a docker host with some images and no containers, removing the image "busy:1" conflicts with a container.
+type fakeClient struct {
+	client.APIClient
+	images  []*image.Summary
+	removed []string
+}
+
+func (c *fakeClient) DiskUsage(context.Context, types.DiskUsageOptions) (types.DiskUsage, error) {
+	du := types.DiskUsage{Images: c.images}
+	for _, img := range c.images {
+		du.LayersSize += img.Size
+	}
+	return du, nil
+}
+
+func (c *fakeClient) ContainerList(context.Context, container.ListOptions) ([]types.Container, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) ImageRemove(_ context.Context, ref string, opts types.ImageRemoveOptions) ([]image.DeleteResponse, error) {
+	if opts.Force {
+		return nil, errors.New("forced")
+	}
+	if ref == "busy:1" {
+		return nil, errdefs.Conflict(errors.New("image is being used by a running container"))
+	}
+	c.removed = append(c.removed, ref)
+	return nil, nil
+}
+
+func TestCollect(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "usage.json"))
+	tracker.Hold("app:1", "app:latest", "busy:1")()
+	cli := &fakeClient{images: []*image.Summary{
+		{ID: "sha256:a", RepoTags: []string{"app:1", "app:latest"}, Size: 10},
+		{ID: "sha256:b", RepoTags: []string{"busy:1"}, Size: 10},
+		{ID: "sha256:c", RepoTags: []string{"<none>:<none>"}, Size: 10},
+	}}
+
+	result, err := Collect(context.Background(), cli, tracker, Policy{All: true, MaxSize: 1}, false)
+	require.NoError(t, err)
+	// the image with several tags is removed by its tags, and the one in use is kept
+	assert.ElementsMatch(t, []string{"sha256:c", "app:1", "app:latest"}, cli.removed)
+	assert.ElementsMatch(t, []string{"sha256:c", "app:1"}, names(result.Removed))
+	assert.Equal(t, 1, result.Kept)
+
+	usage, err := tracker.Usage()
+	require.NoError(t, err)
+	assert.NotContains(t, usage, "docker.io/library/app:1")
+	assert.NotContains(t, usage, "docker.io/library/app:latest")
+	assert.Contains(t, usage, "docker.io/library/busy:1")
+}
+
+func TestCollect_dangling(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "usage.json"))
+	tracker.Hold("app:1")()
+	cli := &fakeClient{images: []*image.Summary{
+		{ID: "sha256:a", RepoTags: []string{"app:1"}, Size: 10},
+		{ID: "sha256:other", RepoTags: []string{"<none>:<none>"}, Size: 10},
+	}}
+
+	// the dangling image of another tool is kept, and the image used by the runner is adopted
+	result, err := Collect(context.Background(), cli, tracker, Policy{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+	assert.Equal(t, 2, result.Kept)
+
+	// the tag has been moved to a newer image
+	tracker.Hold("app:1")()
+	cli.images = []*image.Summary{
+		{ID: "sha256:b", RepoTags: []string{"app:1"}, Size: 10},
+		{ID: "sha256:a", RepoTags: []string{"<none>:<none>"}, Size: 10},
+		{ID: "sha256:other", RepoTags: []string{"<none>:<none>"}, Size: 10},
+	}
+	result, err = Collect(context.Background(), cli, tracker, Policy{}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sha256:a"}, cli.removed)
+	assert.Equal(t, []string{"sha256:a"}, names(result.Removed))
+
+	usage, err := tracker.Usage()
+	require.NoError(t, err)
+	assert.NotContains(t, usage, "sha256:a")
+	assert.Contains(t, usage, "sha256:b")
+	assert.NotContains(t, usage, "sha256:other")
+}
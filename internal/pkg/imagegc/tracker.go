@@ -0,0 +1,170 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package imagegc removes the docker images which the runner doesn't use anymore.
+package imagegc
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// Tracker records the images used by the tasks of the runner.
+// The images held by running tasks are only known to the process, while the time each image was used last
+// is saved to a file, so the images used by the runner are known to the gc command as well.
+type Tracker struct {
+	file string // file is where the usage is saved, empty means the usage is not saved.
+
+	mu   sync.Mutex
+	held map[string]int
+}
+
+// NewTracker returns a tracker saving the usage to file.
+func NewTracker(file string) *Tracker {
+	return &Tracker{
+		file: file,
+		held: map[string]int{},
+	}
+}
+
+// Normalize returns the image in the form used by the tracker, like "docker.io/library/node:18" for "node:18".
+// Images which can't be parsed, like image IDs, are returned as they are.
+func Normalize(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+// Hold marks the images as used by a running task until release is called, and records the use.
+func (t *Tracker) Hold(images ...string) (release func()) {
+	if t == nil {
+		return func() {}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	normalized := make([]string, 0, len(images))
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		image = Normalize(image)
+		t.held[image]++
+		normalized = append(normalized, image)
+	}
+	_ = t.touch(normalized, time.Now())
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			for _, image := range normalized {
+				if t.held[image]--; t.held[image] <= 0 {
+					delete(t.held, image)
+				}
+			}
+			// the images are used until the task finishes
+			_ = t.touch(normalized, time.Now())
+		})
+	}
+}
+
+// Held reports whether the image is held by a running task.
+func (t *Tracker) Held(image string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.held[Normalize(image)] > 0
+}
+
+// Usage returns the time each image was used last, and the time each image adopted by the runner was adopted, by its ID.
+func (t *Tracker) Usage() (map[string]time.Time, error) {
+	if t == nil {
+		return map[string]time.Time{}, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.load()
+}
+
+// Forget removes the images, or the IDs of the adopted ones, from the usage after they have been removed.
+func (t *Tracker) Forget(images ...string) error {
+	if t == nil || t.file == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage, err := t.load()
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		delete(usage, image)
+		delete(usage, Normalize(image))
+	}
+	return t.save(usage)
+}
+
+// Adopt records the images with the given IDs as the runner's, so they are still collected after they have become dangling,
+// when their tags have been moved to newer images.
+func (t *Tracker) Adopt(ids ...string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.touch(ids, time.Now())
+}
+
+func (t *Tracker) touch(images []string, now time.Time) error {
+	if t.file == "" || len(images) == 0 {
+		return nil
+	}
+	usage, err := t.load()
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		usage[image] = now
+	}
+	return t.save(usage)
+}
+
+func (t *Tracker) load() (map[string]time.Time, error) {
+	usage := map[string]time.Time{}
+	if t.file == "" {
+		return usage, nil
+	}
+	content, err := os.ReadFile(t.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (t *Tracker) save(usage map[string]time.Time) error {
+	content, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.file)
+}
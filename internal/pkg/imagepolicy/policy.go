@@ -0,0 +1,111 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package imagepolicy decides which images jobs are allowed to run containers with.
+package imagepolicy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/gobwas/glob"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy checks images against the allowed patterns, and pins them to digests.
+type Policy struct {
+	allowed       []glob.Glob
+	requireDigest bool
+	lock          map[string]string
+}
+
+// New returns a policy.
+// An image must match one of the allowed glob patterns, any image is allowed if there are none.
+// If requireDigest is true, an image must be referenced by digest, after it has been pinned by the lock file.
+// The lock file is a YAML map from images to their digests, like `node:18: sha256:...`, it's ignored if it's empty.
+func New(allowed []string, requireDigest bool, lockFile string) (*Policy, error) {
+	p := &Policy{
+		requireDigest: requireDigest,
+		lock:          map[string]string{},
+	}
+	for _, pattern := range allowed {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image pattern %q: %w", pattern, err)
+		}
+		p.allowed = append(p.allowed, g)
+	}
+	if lockFile != "" {
+		content, err := os.ReadFile(lockFile)
+		if err != nil {
+			return nil, fmt.Errorf("read image lock file %q: %w", lockFile, err)
+		}
+		var lock map[string]string
+		if err := yaml.Unmarshal(content, &lock); err != nil {
+			return nil, fmt.Errorf("parse image lock file %q: %w", lockFile, err)
+		}
+		for image, digest := range lock {
+			named, err := reference.ParseNormalizedNamed(image)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image %q in lock file %q: %w", image, lockFile, err)
+			}
+			p.lock[reference.TagNameOnly(named).String()] = digest
+		}
+	}
+	return p, nil
+}
+
+// Enabled reports whether the policy restricts anything.
+func (p *Policy) Enabled() bool {
+	return len(p.allowed) > 0 || p.requireDigest || len(p.lock) > 0
+}
+
+// Check returns the image to use instead of the given one, which is pinned to a digest if the lock file has it.
+// It returns an error if the image is not allowed.
+func (p *Policy) Check(image string) (string, error) {
+	if strings.Contains(image, "${{") {
+		return "", fmt.Errorf("image %q contains an expression, which can't be checked before the job runs", image)
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %q: %w", image, err)
+	}
+
+	if !p.allow(image, named) {
+		return "", fmt.Errorf("image %q is not allowed", image)
+	}
+
+	pinned := image
+	if _, ok := named.(reference.Digested); !ok {
+		if digest, ok := p.lock[reference.TagNameOnly(named).String()]; ok {
+			pinned = reference.FamiliarName(named) + "@" + digest
+		}
+	}
+	if p.requireDigest {
+		if named, err := reference.ParseNormalizedNamed(pinned); err != nil {
+			return "", fmt.Errorf("invalid image %q: %w", pinned, err)
+		} else if _, ok := named.(reference.Digested); !ok {
+			return "", fmt.Errorf("image %q is not referenced by digest, and the lock file doesn't pin it", image)
+		}
+	}
+	return pinned, nil
+}
+
+// allow reports whether the image matches any allowed pattern,
+// either as it's written, or in its familiar or fully qualified form, like "node:18" and "docker.io/library/node:18".
+func (p *Policy) allow(image string, named reference.Named) bool {
+	if len(p.allowed) == 0 {
+		return true
+	}
+	forms := []string{image, reference.FamiliarString(named), named.String()}
+	for _, g := range p.allowed {
+		for _, form := range forms {
+			if g.Match(form) {
+				return true
+			}
+		}
+	}
+	return false
+}
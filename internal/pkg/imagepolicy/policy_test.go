@@ -0,0 +1,52 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package imagepolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+
+func TestPolicy_Check(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "images.lock")
+	require.NoError(t, os.WriteFile(lockFile, []byte("node:18: "+digest+"\n"), 0o644))
+
+	tests := []struct {
+		name          string
+		allowed       []string
+		requireDigest bool
+		image         string
+		want          string
+		wantErr       bool
+	}{
+		{name: "no restrictions", image: "alpine", want: "alpine"},
+		{name: "familiar form", allowed: []string{"node:*"}, image: "node:20", want: "node:20"},
+		{name: "qualified form", allowed: []string{"docker.io/library/*"}, image: "node:20", want: "node:20"},
+		{name: "not allowed", allowed: []string{"docker.gitea.com/*"}, image: "node:20", wantErr: true},
+		{name: "pinned", image: "docker.io/library/node:18", want: "node@" + digest},
+		{name: "digest required", requireDigest: true, image: "node:20", wantErr: true},
+		{name: "digest required and pinned", requireDigest: true, image: "node:18", want: "node@" + digest},
+		{name: "digest required and given", requireDigest: true, image: "alpine@" + digest, want: "alpine@" + digest},
+		{name: "expression", allowed: []string{"*"}, image: "${{ matrix.image }}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.allowed, tt.requireDigest, lockFile)
+			require.NoError(t, err)
+			got, err := p.Check(tt.image)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package labels
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// tools are the executables which are detected on the host, mapped to the label names they add.
+var tools = map[string]string{
+	"docker":                "docker",
+	"podman":                "podman",
+	"qemu-img":              "qemu",
+	"qemu-system-x86_64":    "qemu",
+	"qemu-system-aarch64":   "qemu",
+	"qemu-system-riscv64":   "qemu",
+	"qemu-system-ppc64":     "qemu",
+	"qemu-system-s390x":     "qemu",
+	"qemu-system-loongarch": "qemu",
+}
+
+// Detect inspects the host and returns labels describing it, every name is prefixed with prefix.
+// The labels are the OS like "linux", the OS and arch like "linux-arm64", the CPU count like "cpu-8",
+// the memory tier like "mem-16g", and the available virtualization and container tools like "docker" or "kvm".
+// They are host labels, so they are only detected if the configured labels have one,
+// otherwise detecting them would enable running jobs on the host, and it returns nil.
+func Detect(prefix string, configured Labels) Labels {
+	if !configured.hasHost() {
+		return nil
+	}
+
+	names := []string{
+		runtime.GOOS,
+		runtime.GOOS + "-" + runtime.GOARCH,
+		"cpu-" + strconv.Itoa(runtime.NumCPU()),
+	}
+	if total, err := memoryTotal(); err == nil && total > 0 {
+		names = append(names, fmt.Sprintf("mem-%dg", memoryTier(total)))
+	}
+
+	seen := map[string]bool{}
+	for bin, name := range tools {
+		if seen[name] {
+			continue
+		}
+		if _, err := exec.LookPath(bin); err == nil {
+			seen[name] = true
+		}
+	}
+	for _, name := range []string{"docker", "podman", "qemu"} {
+		if seen[name] {
+			names = append(names, name)
+		}
+	}
+	if _, err := os.Stat("/dev/kvm"); err == nil {
+		names = append(names, "kvm")
+	}
+
+	ls := make(Labels, 0, len(names))
+	for _, name := range names {
+		ls = append(ls, &Label{
+			Name:   prefix + name,
+			Schema: SchemeHost,
+		})
+	}
+	return ls
+}
+
+// hasHost returns whether any of the labels runs jobs on the host.
+func (l Labels) hasHost() bool {
+	for _, label := range l {
+		if label.Schema == SchemeHost {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge returns l with the labels of other appended, except the ones whose names l already has.
+func (l Labels) Merge(other Labels) Labels {
+	names := make(map[string]bool, len(l))
+	for _, label := range l {
+		for _, name := range label.names() {
+			names[name] = true
+		}
+	}
+	merged := append(Labels{}, l...)
+	for _, label := range other {
+		if !names[label.Name] {
+			merged = append(merged, label)
+		}
+	}
+	return merged
+}
+
+// memoryTier rounds the memory size down to a power of two in GiB, it's at least 1.
+// The total memory reported by the OS is a bit less than the installed memory,
+// so 10% is added before rounding, to make a 16 GiB host "mem-16g" rather than "mem-8g".
+func memoryTier(bytes uint64) uint64 {
+	gib := bytes * 11 / 10 >> 30
+	tier := uint64(1)
+	for tier*2 <= gib {
+		tier *= 2
+	}
+	return tier
+}
+
+// memoryTotal returns the total memory of the host in bytes.
+// It's only supported on Linux, since it reads /proc/meminfo.
+func memoryTotal() (uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// like "MemTotal:       16318412 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb << 10, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("MemTotal not found")
+}
@@ -5,6 +5,8 @@ package labels
 
 import (
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 )
 
@@ -13,19 +15,43 @@ const (
 	SchemeDocker = "docker"
 )
 
+const (
+	// PlatformHost is the platform picked for labels with the host scheme.
+	PlatformHost = "-self-hosted"
+	// DefaultImage is the image used for jobs whose labels the runner doesn't have.
+	DefaultImage = "docker.gitea.com/runner-images:ubuntu-latest"
+)
+
 type Label struct {
-	Name   string
-	Schema string
-	Arg    string
+	Name    string
+	Aliases []string // Aliases are the other names of the label, they are written like "name|alias1|alias2:docker://image".
+	Schema  string
+	Arg     string
+	Options *Options // Options are the container options of a docker label, see Options.
 }
 
+// Parse parses a label like "name:schema:arg".
+// The name could be followed by aliases separated by "|", and every name could be a wildcard pattern with "*",
+// like "ubuntu-*|linux:docker://image".
 func Parse(str string) (*Label, error) {
 	splits := strings.SplitN(str, ":", 3)
+	names := strings.Split(splits[0], "|")
 	label := &Label{
-		Name:   splits[0],
+		Name:   names[0],
 		Schema: "host",
 		Arg:    "",
 	}
+	if len(names) > 1 {
+		label.Aliases = names[1:]
+	}
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("empty label name: %s", str)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("invalid label name %q: %w", name, err)
+		}
+	}
 	if len(splits) >= 2 {
 		label.Schema = splits[1]
 	}
@@ -35,6 +61,14 @@ func Parse(str string) (*Label, error) {
 	if label.Schema != SchemeHost && label.Schema != SchemeDocker {
 		return nil, fmt.Errorf("unsupported schema: %s", label.Schema)
 	}
+	if arg, query, ok := strings.Cut(label.Arg, "?"); ok && label.Schema == SchemeDocker {
+		opts, err := parseOptions(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %q: %w", label.Name, err)
+		}
+		label.Arg = arg
+		label.Options = opts
+	}
 	return label, nil
 }
 
@@ -49,43 +83,124 @@ func (l Labels) RequireDocker() bool {
 	return false
 }
 
-func (l Labels) PickPlatform(runsOn []string) string {
-	platforms := make(map[string]string, len(l))
-	for _, label := range l {
-		switch label.Schema {
-		case SchemeDocker:
-			// "//" will be ignored
-			platforms[label.Name] = strings.TrimPrefix(label.Arg, "//")
-		case SchemeHost:
-			platforms[label.Name] = "-self-hosted"
-		default:
-			// It should not happen, because Parse has checked it.
-			continue
+// Platform returns the platform which act runs a job with the label on,
+// it's the image for docker labels and PlatformHost for host labels.
+func (l *Label) Platform() string {
+	if l.Schema == SchemeDocker {
+		// "//" will be ignored
+		return strings.TrimPrefix(l.Arg, "//")
+	}
+	return PlatformHost
+}
+
+// names returns the name and the aliases of the label.
+func (l *Label) names() []string {
+	return append([]string{l.Name}, l.Aliases...)
+}
+
+// exactMatch is the specificity of a name which matches without wildcards.
+const exactMatch = 1 << 16
+
+// match returns how specific the label matches the given runs-on label, 0 means it doesn't match.
+// A name or an alias equal to it is the most specific, followed by wildcard patterns with more literal characters.
+func (l *Label) match(runsOn string) int {
+	score := 0
+	for _, name := range l.names() {
+		if name == runsOn {
+			return exactMatch
+		}
+		if ok, _ := path.Match(name, runsOn); ok && strings.Contains(name, "*") {
+			score = max(score, len(name)-strings.Count(name, "*")+1)
 		}
 	}
+	return score
+}
+
+// Pick returns the label which a job with the given runs-on labels should run with.
+// Every one of runsOn must be satisfied by the labels, otherwise it returns false.
+// The most specific label is picked, which means a docker label is preferred to a host label,
+// since the image determines the environment the job runs in.
+// Between labels of the same scheme, an exact name is preferred to a wildcard,
+// and then the first one in runsOn wins.
+func (l Labels) Pick(runsOn []string) (*Label, bool) {
+	var (
+		picked      *Label
+		pickedScore int
+	)
 	for _, v := range runsOn {
-		if v, ok := platforms[v]; ok {
-			return v
+		label, score := l.find(v)
+		if label == nil {
+			return nil, false
+		}
+		switch {
+		case picked == nil,
+			picked.Schema != SchemeDocker && label.Schema == SchemeDocker,
+			picked.Schema == label.Schema && score > pickedScore:
+			picked, pickedScore = label, score
 		}
 	}
+	return picked, picked != nil
+}
 
-	// TODO: support multiple labels
-	// like:
-	//   ["ubuntu-22.04"] => "ubuntu:22.04"
-	//   ["with-gpu"] => "linux:with-gpu"
-	//   ["ubuntu-22.04", "with-gpu"] => "ubuntu:22.04_with-gpu"
+// find returns the label which matches the given runs-on label most specifically.
+func (l Labels) find(runsOn string) (*Label, int) {
+	var (
+		found      *Label
+		foundScore int
+	)
+	for _, label := range l {
+		if score := label.match(runsOn); score > foundScore {
+			found, foundScore = label, score
+		}
+	}
+	return found, foundScore
+}
 
-	// return default.
-	// So the runner receives a task with a label that the runner doesn't have,
-	// it happens when the user have edited the label of the runner in the web UI.
-	// TODO: it may be not correct, what if the runner is used as host mode only?
-	return "docker.gitea.com/runner-images:ubuntu-latest"
+// FallbackPlatform returns the platform for jobs whose runs-on labels can't be satisfied.
+// It's the default image, or PlatformHost if the runner has no docker labels.
+func (l Labels) FallbackPlatform() string {
+	if len(l) > 0 && !l.RequireDocker() {
+		return PlatformHost
+	}
+	return DefaultImage
+}
+
+// PickPlatform returns the platform for the given runs-on labels,
+// or FallbackPlatform if they can't be satisfied.
+func (l Labels) PickPlatform(runsOn []string) string {
+	if label, ok := l.Pick(runsOn); ok {
+		return label.Platform()
+	}
+	return l.FallbackPlatform()
 }
 
+// Images returns the images of the docker labels, without duplicates.
+func (l Labels) Images() []string {
+	var images []string
+	for _, label := range l {
+		if label.Schema != SchemeDocker {
+			continue
+		}
+		if image := label.Platform(); image != "" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// Names returns the concrete names of the labels, including the aliases, to declare to Gitea.
+// Wildcard patterns are left out, since Gitea matches the labels of jobs literally.
 func (l Labels) Names() []string {
 	names := make([]string, 0, len(l))
+	seen := make(map[string]bool, len(l))
 	for _, label := range l {
-		names = append(names, label.Name)
+		for _, name := range label.names() {
+			if strings.Contains(name, "*") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
 	}
 	return names
 }
@@ -93,12 +208,15 @@ func (l Labels) Names() []string {
 func (l Labels) ToStrings() []string {
 	ls := make([]string, 0, len(l))
 	for _, label := range l {
-		lbl := label.Name
+		lbl := strings.Join(label.names(), "|")
 		if label.Schema != "" {
 			lbl += ":" + label.Schema
 			if label.Arg != "" {
 				lbl += ":" + label.Arg
 			}
+			if label.Options != nil {
+				lbl += "?" + label.Options.Encode()
+			}
 		}
 		ls = append(ls, lbl)
 	}
@@ -4,6 +4,8 @@
 package labels
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -43,6 +45,40 @@ func TestParse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			args: "ubuntu-latest|ubuntu-24.04|linux:docker://node:18",
+			want: &Label{
+				Name:    "ubuntu-latest",
+				Aliases: []string{"ubuntu-24.04", "linux"},
+				Schema:  "docker",
+				Arg:     "//node:18",
+			},
+			wantErr: false,
+		},
+		{
+			args: "dind:docker://docker:dind?privileged=true&network=host&cpus=4",
+			want: &Label{
+				Name:   "dind",
+				Schema: "docker",
+				Arg:    "//docker:dind",
+				Options: &Options{
+					Privileged: &[]bool{true}[0],
+					Network:    "host",
+					Flags:      []Flag{{Name: "cpus", Value: "4"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			args:    "dind:docker://docker:dind?mount=/etc",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			args:    "ubuntu||linux:host",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			args:    "ubuntu:vm:ubuntu-18.04",
 			want:    nil,
@@ -61,3 +97,138 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestLabels_PickPlatform(t *testing.T) {
+	mustParse := func(strs ...string) Labels {
+		ls := Labels{}
+		for _, s := range strs {
+			l, err := Parse(s)
+			require.NoError(t, err)
+			ls = append(ls, l)
+		}
+		return ls
+	}
+
+	tests := []struct {
+		name   string
+		labels Labels
+		runsOn []string
+		want   string
+	}{
+		{
+			name:   "single label",
+			labels: mustParse("ubuntu:docker://node:18", "macos:host"),
+			runsOn: []string{"ubuntu"},
+			want:   "node:18",
+		},
+		{
+			name:   "docker label is more specific than host label",
+			labels: mustParse("gpu:host", "ubuntu:docker://node:18"),
+			runsOn: []string{"gpu", "ubuntu"},
+			want:   "node:18",
+		},
+		{
+			name:   "first docker label wins",
+			labels: mustParse("ubuntu:docker://node:18", "debian:docker://node:20"),
+			runsOn: []string{"debian", "ubuntu"},
+			want:   "node:20",
+		},
+		{
+			name:   "alias",
+			labels: mustParse("ubuntu-latest|linux:docker://node:18"),
+			runsOn: []string{"linux"},
+			want:   "node:18",
+		},
+		{
+			name:   "wildcard",
+			labels: mustParse("ubuntu-*:docker://node:18", "macos:host"),
+			runsOn: []string{"ubuntu-22.04"},
+			want:   "node:18",
+		},
+		{
+			name:   "exact name is more specific than wildcard",
+			labels: mustParse("ubuntu-*:docker://node:18", "ubuntu-22.04:docker://node:20"),
+			runsOn: []string{"ubuntu-22.04"},
+			want:   "node:20",
+		},
+		{
+			name:   "longer wildcard is more specific",
+			labels: mustParse("*:docker://node:16", "ubuntu-*:docker://node:18"),
+			runsOn: []string{"ubuntu-22.04"},
+			want:   "node:18",
+		},
+		{
+			name:   "not all labels satisfied",
+			labels: mustParse("ubuntu:docker://node:18"),
+			runsOn: []string{"ubuntu", "gpu"},
+			want:   DefaultImage,
+		},
+		{
+			name:   "host only runner falls back to host",
+			labels: mustParse("macos:host"),
+			runsOn: []string{"ubuntu"},
+			want:   PlatformHost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.labels.PickPlatform(tt.runsOn))
+		})
+	}
+}
+
+func TestLabels_Names(t *testing.T) {
+	ls := Labels{}
+	for _, s := range []string{"ubuntu-latest|ubuntu-24.04|linux:docker://node:18", "ubuntu-*:docker://node:18?memory=4g&pids-limit=100", "linux:host"} {
+		l, err := Parse(s)
+		require.NoError(t, err)
+		ls = append(ls, l)
+	}
+	assert.DeepEqual(t, []string{"ubuntu-latest", "ubuntu-24.04", "linux"}, ls.Names())
+	assert.DeepEqual(t, []string{"ubuntu-latest|ubuntu-24.04|linux:docker://node:18", "ubuntu-*:docker://node:18?memory=4g&pids-limit=100", "linux:host"}, ls.ToStrings())
+}
+
+func TestLabels_Merge(t *testing.T) {
+	configured := Labels{
+		{Name: "linux", Aliases: []string{"kvm"}, Schema: SchemeDocker, Arg: "//node:18"},
+	}
+	merged := configured.Merge(Labels{
+		{Name: "linux", Schema: SchemeHost},
+		{Name: "kvm", Schema: SchemeHost},
+		{Name: "cpu-8", Schema: SchemeHost},
+	})
+	assert.DeepEqual(t, []string{"linux|kvm:docker://node:18", "cpu-8:host"}, merged.ToStrings())
+}
+
+func TestDetect(t *testing.T) {
+	docker := Labels{{Name: "ubuntu", Schema: SchemeDocker, Arg: "//node:18"}}
+	// a runner without host labels never runs jobs on the host because of the detected labels
+	assert.Assert(t, Detect("host-", docker) == nil)
+
+	detected := Detect("host-", append(docker, &Label{Name: "self-hosted", Schema: SchemeHost}))
+	assert.Assert(t, len(detected) >= 3)
+	assert.DeepEqual(t, []string{"host-" + runtime.GOOS, "host-" + runtime.GOOS + "-" + runtime.GOARCH, fmt.Sprintf("host-cpu-%d", runtime.NumCPU())}, detected.Names()[:3])
+	for _, label := range detected {
+		assert.Equal(t, SchemeHost, label.Schema)
+		assert.Equal(t, PlatformHost, label.Platform())
+	}
+}
+
+func TestMemoryTier(t *testing.T) {
+	tests := []struct {
+		bytes uint64
+		want  uint64
+	}{
+		{0, 1},
+		{512 << 20, 1},
+		{2<<30 - 100<<20, 2},
+		{12 << 30, 8},
+		{15<<30 + 600<<20, 16},
+		{31 << 30, 32},
+		{64 << 30, 64},
+		{100 << 30, 64},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, memoryTier(tt.bytes), "%d bytes", tt.bytes)
+	}
+}
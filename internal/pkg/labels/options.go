@@ -0,0 +1,114 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package labels
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Options are the container options of a docker label.
+// They are written as a query string after the image, like "dind:docker://image?privileged=true&network=host&cpus=4",
+// and take precedence over the container section of the config for jobs running with the label.
+type Options struct {
+	Privileged *bool  // Privileged overrides container.privileged.
+	Network    string // Network overrides container.network.
+	DockerHost string // DockerHost overrides the docker host mounted to the job container, "-" means not to mount it. The containers are still created on the docker host of the runner.
+	Flags      []Flag // Flags are passed to the job container as docker run flags, in order.
+}
+
+// Flag is a docker run flag like "--cpus=4".
+type Flag struct {
+	Name  string
+	Value string
+}
+
+func (f Flag) String() string {
+	return fmt.Sprintf("--%s=%s", f.Name, strconv.Quote(f.Value))
+}
+
+// allowedFlags are the docker run flags which can be set in the options of a label.
+var allowedFlags = map[string]bool{
+	"add-host":           true,
+	"cap-add":            true,
+	"cap-drop":           true,
+	"cpu-shares":         true,
+	"cpus":               true,
+	"cpuset-cpus":        true,
+	"device":             true,
+	"dns":                true,
+	"gpus":               true,
+	"memory":             true,
+	"memory-reservation": true,
+	"memory-swap":        true,
+	"pids-limit":         true,
+	"runtime":            true,
+	"shm-size":           true,
+	"tmpfs":              true,
+	"ulimit":             true,
+	"user":               true,
+}
+
+// parseOptions parses the query string of a docker label.
+// The order of the flags is kept, so the label can be written back as it was.
+func parseOptions(query string) (*Options, error) {
+	opts := &Options{}
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		value, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of option %q: %w", key, err)
+		}
+		switch key {
+		case "privileged":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of option %q: %w", key, err)
+			}
+			opts.Privileged = &b
+		case "network":
+			opts.Network = value
+		case "docker_host":
+			opts.DockerHost = value
+		default:
+			if !allowedFlags[key] {
+				return nil, fmt.Errorf("unsupported option: %s", key)
+			}
+			opts.Flags = append(opts.Flags, Flag{Name: key, Value: value})
+		}
+	}
+	return opts, nil
+}
+
+// Encode returns the options as a query string.
+func (o *Options) Encode() string {
+	var pairs []string
+	if o.Privileged != nil {
+		pairs = append(pairs, "privileged="+strconv.FormatBool(*o.Privileged))
+	}
+	if o.Network != "" {
+		pairs = append(pairs, "network="+url.QueryEscape(o.Network))
+	}
+	if o.DockerHost != "" {
+		pairs = append(pairs, "docker_host="+url.QueryEscape(o.DockerHost))
+	}
+	for _, f := range o.Flags {
+		pairs = append(pairs, f.Name+"="+url.QueryEscape(f.Value))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// ContainerOptions returns the flags as options for the job container.
+func (o *Options) ContainerOptions() string {
+	flags := make([]string, 0, len(o.Flags))
+	for _, f := range o.Flags {
+		flags = append(flags, f.String())
+	}
+	return strings.Join(flags, " ")
+}
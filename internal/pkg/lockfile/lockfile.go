@@ -0,0 +1,48 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package lockfile locks files between processes, like the daemon and the commands which must not run along with it.
+// The locks are advisory, and they are released when the process exits.
+package lockfile
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned when another process holds a lock on the file.
+var ErrLocked = errors.New("the file is locked by another process")
+
+// File is a locked file.
+type File struct {
+	f *os.File
+}
+
+// Shared takes a shared lock on the file at path, which is created if it doesn't exist.
+// Several processes can hold a shared lock at once, it waits until an exclusive lock has been released.
+func Shared(path string) (*File, error) {
+	return lock(path, false)
+}
+
+// TryExclusive takes an exclusive lock on the file at path, which is created if it doesn't exist.
+// It doesn't wait, it returns ErrLocked if another process holds a lock on the file.
+func TryExclusive(path string) (*File, error) {
+	return lock(path, true)
+}
+
+func lock(path string, exclusive bool) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &File{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *File) Unlock() error {
+	return l.f.Close()
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package lockfile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+
+	shared, err := Shared(path)
+	require.NoError(t, err)
+	other, err := Shared(path)
+	require.NoError(t, err)
+
+	_, err = TryExclusive(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, shared.Unlock())
+	_, err = TryExclusive(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, other.Unlock())
+	exclusive, err := TryExclusive(path)
+	require.NoError(t, err)
+	require.NoError(t, exclusive.Unlock())
+}
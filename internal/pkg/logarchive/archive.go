@@ -0,0 +1,195 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package logarchive keeps a local copy of the logs of tasks.
+package logarchive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+// Archive stores the log of every task in a file like "<dir>/<owner>/<repo>/<run>/<task>.log".
+type Archive struct {
+	Dir  string
+	Gzip bool // Gzip indicates whether the files are gzipped, they are named like "<task>.log.gz" then.
+}
+
+// Path returns the file which the log of the task is stored in.
+func (a *Archive) Path(repo, runID string, taskID int64) string {
+	name := strconv.FormatInt(taskID, 10) + ".log"
+	if a.Gzip {
+		name += ".gz"
+	}
+	return filepath.Join(a.Dir, safePath(repo), safePath(runID), name)
+}
+
+// safePath returns the slash separated path as a relative one, so it can't escape the archive directory.
+func safePath(path string) string {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `\:`) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return filepath.Join(parts...)
+}
+
+// Create creates the file of the task, an existing one is truncated.
+func (a *Archive) Create(repo, runID string, taskID int64) (*Log, error) {
+	path := a.Path(repo, runID, taskID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{
+		path:   path,
+		file:   f,
+		writer: f,
+	}
+	if a.Gzip {
+		l.gzip = gzip.NewWriter(f)
+		l.writer = l.gzip
+	}
+	return l, nil
+}
+
+// Log is the archived log of a task. All the methods of a nil Log do nothing.
+type Log struct {
+	path string
+
+	mu        sync.Mutex
+	file      *os.File
+	gzip      *gzip.Writer
+	writer    io.Writer
+	failed    bool     // failed indicates whether a write has failed, the log isn't written anymore then.
+	stepNames []string // stepNames are the names of the steps, by index.
+}
+
+// Path returns the file of the log.
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// SetStepNames sets the names of the steps, which are used by the step boundaries.
+func (l *Log) SetStepNames(names []string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stepNames = names
+}
+
+// WriteRow writes a log row, which should have been masked already.
+func (l *Log) WriteRow(row *runnerv1.LogRow) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(row.Time.AsTime(), row.Content)
+}
+
+// StepStarted writes the boundary of the beginning of a step.
+func (l *Log) StepStarted(step int64, at time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(at, fmt.Sprintf("=== %s started", l.stepName(step)))
+}
+
+// StepStopped writes the boundary of the end of a step, with its result.
+func (l *Log) StepStopped(step int64, result runnerv1.Result, at time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(at, fmt.Sprintf("=== %s finished: %s", l.stepName(step), resultName(result)))
+}
+
+// JobStopped writes the result of the job.
+func (l *Log) JobStopped(result runnerv1.Result, at time.Time) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(at, fmt.Sprintf("=== job finished: %s", resultName(result)))
+}
+
+func (l *Log) stepName(step int64) string {
+	if step >= 0 && step < int64(len(l.stepNames)) {
+		return fmt.Sprintf("step %d (%s)", step+1, l.stepNames[step])
+	}
+	return fmt.Sprintf("step %d", step+1)
+}
+
+// resultName returns the result like "success".
+func resultName(result runnerv1.Result) string {
+	return strings.ToLower(strings.TrimPrefix(result.String(), "RESULT_"))
+}
+
+func (l *Log) write(at time.Time, content string) {
+	if l.failed || l.writer == nil {
+		return
+	}
+	// a row could contain several lines, each of them is prefixed with the time
+	var sb strings.Builder
+	ts := at.UTC().Format(time.RFC3339Nano)
+	for _, line := range strings.Split(content, "\n") {
+		sb.WriteString(ts)
+		sb.WriteByte(' ')
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	if _, err := io.WriteString(l.writer, sb.String()); err != nil {
+		l.failed = true
+		log.WithError(err).Errorf("failed to write the log archive %s, it won't be written anymore", l.path)
+	}
+}
+
+// Close flushes and closes the file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	var err error
+	if l.gzip != nil {
+		err = l.gzip.Close()
+	}
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file, l.gzip, l.writer = nil, nil, nil
+	return err
+}
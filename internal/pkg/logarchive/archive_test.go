@@ -0,0 +1,74 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package logarchive
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestArchive_Path(t *testing.T) {
+	a := &Archive{Dir: "/logs"}
+	assert.Equal(t, filepath.FromSlash("/logs/owner/repo/12/34.log"), a.Path("owner/repo", "12", 34))
+	assert.Equal(t, filepath.FromSlash("/logs/etc/passwd/unknown/34.log"), a.Path("../../etc/passwd", "", 34))
+
+	a.Gzip = true
+	assert.Equal(t, filepath.FromSlash("/logs/owner/repo/12/34.log.gz"), a.Path("owner/repo", "12", 34))
+}
+
+func TestLog(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := "2026-01-02T03:04:05Z received task\n" +
+		"2026-01-02T03:04:05Z === step 1 (Checkout) started\n" +
+		"2026-01-02T03:04:05Z first line\n" +
+		"2026-01-02T03:04:05Z second line\n" +
+		"2026-01-02T03:04:05Z === step 1 (Checkout) finished: success\n" +
+		"2026-01-02T03:04:05Z === step 2 started\n" +
+		"2026-01-02T03:04:05Z === step 2 finished: failure\n" +
+		"2026-01-02T03:04:05Z === job finished: failure\n"
+
+	for _, gz := range []bool{false, true} {
+		a := &Archive{Dir: t.TempDir(), Gzip: gz}
+		l, err := a.Create("owner/repo", "12", 34)
+		require.NoError(t, err)
+		l.SetStepNames([]string{"Checkout"})
+		l.WriteRow(&runnerv1.LogRow{Time: timestamppb.New(at), Content: "received task"})
+		l.StepStarted(0, at)
+		l.WriteRow(&runnerv1.LogRow{Time: timestamppb.New(at), Content: "first line\nsecond line"})
+		l.StepStopped(0, runnerv1.Result_RESULT_SUCCESS, at)
+		l.StepStarted(1, at)
+		l.StepStopped(1, runnerv1.Result_RESULT_FAILURE, at)
+		l.JobStopped(runnerv1.Result_RESULT_FAILURE, at)
+		require.NoError(t, l.Close())
+		require.NoError(t, l.Close())
+
+		f, err := os.Open(l.Path())
+		require.NoError(t, err)
+		var r io.Reader = f
+		if gz {
+			r, err = gzip.NewReader(f)
+			require.NoError(t, err)
+		}
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		f.Close()
+		assert.Equal(t, want, string(content))
+	}
+}
+
+func TestLog_Nil(t *testing.T) {
+	var l *Log
+	l.WriteRow(&runnerv1.LogRow{Content: "ignored"})
+	l.StepStarted(0, time.Now())
+	assert.NoError(t, l.Close())
+}
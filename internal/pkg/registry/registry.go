@@ -0,0 +1,246 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package registry applies the credentials and mirrors of container registries to images.
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/distribution/reference"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/types"
+	registrytypes "github.com/docker/docker/api/types/registry"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+// dockerHubAuthKey is the key docker uses for the credentials of Docker Hub.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// Registry is a container registry with its credentials and mirror.
+type Registry struct {
+	Host     string // Host is the domain of the registry, like "docker.io" or "ghcr.io".
+	Username string
+	Password string
+	Mirror   string // Mirror is the registry, with an optional path, which images of the registry are pulled from, like "mirror.local/dockerhub".
+}
+
+// Rule rewrites the images matching a pattern, like "docker.io/*" to "mirror.local/*".
+// "*" in From matches any characters, and every "*" in To is replaced by what the "*" at the same position in From matched.
+type Rule struct {
+	From string
+	To   string
+
+	pattern *regexp.Regexp
+}
+
+// NewRule returns the rule rewriting the images matching from to to.
+func NewRule(from, to string) (Rule, error) {
+	if strings.Count(to, "*") > strings.Count(from, "*") {
+		return Rule{}, fmt.Errorf("%q has more wildcards than %q", to, from)
+	}
+	pattern, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(from), `\*`, "(.*)") + "$")
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{From: from, To: to, pattern: pattern}, nil
+}
+
+// apply returns the rewritten image, it's false if the rule doesn't match the image.
+func (r Rule) apply(image string) (string, bool) {
+	m := r.pattern.FindStringSubmatch(image)
+	if m == nil {
+		return "", false
+	}
+	var b strings.Builder
+	for i, part := range strings.Split(r.To, "*") {
+		if i > 0 {
+			b.WriteString(m[i])
+		}
+		b.WriteString(part)
+	}
+	return b.String(), true
+}
+
+// Registries are the known container registries, and the rules rewriting images.
+type Registries struct {
+	List  []Registry
+	Rules []Rule // Rules are applied before the mirrors of the registries, the first matching rule wins.
+}
+
+// Empty returns whether there are neither registries nor rules.
+func (rs Registries) Empty() bool {
+	return len(rs.List) == 0 && len(rs.Rules) == 0
+}
+
+func (rs Registries) find(host string) *Registry {
+	for i := range rs.List {
+		if strings.EqualFold(normalizeHost(rs.List[i].Host), host) {
+			return &rs.List[i]
+		}
+	}
+	return nil
+}
+
+// normalizeHost returns the domain of Docker Hub as the reference package does, and other hosts as they are.
+func normalizeHost(host string) string {
+	switch strings.ToLower(host) {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// Rewrite returns the image to pull instead of the given one.
+// The rules match the full form of the image, like "docker.io/library/node:18" for "node:18".
+// If none matches, it's the same image in the mirror of its registry, like "mirror.local/library/node:18" for "node:18".
+// Images which can't be parsed, or which no rule matches and whose registry has no mirror, are returned as they are.
+func (rs Registries) Rewrite(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	for _, rule := range rs.Rules {
+		if rewritten, ok := rule.apply(named.String()); ok {
+			return rewritten
+		}
+	}
+	r := rs.find(reference.Domain(named))
+	if r == nil || r.Mirror == "" {
+		return image
+	}
+	rewritten := strings.TrimSuffix(r.Mirror, "/") + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		rewritten += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		rewritten += "@" + digested.Digest().String()
+	}
+	return rewritten
+}
+
+// Credentials returns the credentials of the registry of the image.
+func (rs Registries) Credentials(image string) (username, password string, ok bool) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", "", false
+	}
+	r := rs.find(reference.Domain(named))
+	if r == nil || r.Username == "" {
+		return "", "", false
+	}
+	return r.Username, r.Password, true
+}
+
+// EncodedAuth returns the credentials of the registry of the image, encoded for the docker API.
+// It's empty if there are no credentials.
+func (rs Registries) EncodedAuth(image string) (string, error) {
+	username, password, ok := rs.Credentials(image)
+	if !ok {
+		return "", nil
+	}
+	buf, err := json.Marshal(registrytypes.AuthConfig{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(buf), nil
+}
+
+// WriteDockerConfig writes a copy of the current docker config file to dir, with the credentials of the registries,
+// and makes it the docker config of the process, so every pull performed by act could use them.
+// The other registries still get their credentials from the credentials stores and helpers of the current config,
+// the configured ones are exempted from them, since a store would take precedence over the file.
+func (rs Registries) WriteDockerConfig(dir string) error {
+	file, err := dockerconfig.Load(dockerconfig.Dir())
+	if err != nil {
+		return fmt.Errorf("load docker config: %w", err)
+	}
+
+	file.Filename = filepath.Join(dir, dockerconfig.ConfigFileName)
+	if file.AuthConfigs == nil {
+		file.AuthConfigs = map[string]types.AuthConfig{}
+	}
+	if file.CredentialHelpers == nil {
+		file.CredentialHelpers = map[string]string{}
+	}
+	for _, r := range rs.List {
+		if r.Username == "" {
+			continue
+		}
+		key := r.Host
+		// the docker CLI looks up the credentials of Docker Hub by its URL, while act does by its domain
+		hosts := []string{r.Host}
+		if normalizeHost(r.Host) == "docker.io" {
+			key = dockerHubAuthKey
+			hosts = []string{dockerHubAuthKey, "index.docker.io"}
+		}
+		file.AuthConfigs[key] = types.AuthConfig{
+			ServerAddress: key,
+			Username:      r.Username,
+			Password:      r.Password,
+		}
+		for _, host := range hosts {
+			// an empty helper makes the credentials looked up in the file
+			file.CredentialHelpers[host] = ""
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := file.Save(); err != nil {
+		return err
+	}
+	// the directory of the docker config is read from the environment only once, and it has been read above
+	dockerconfig.SetDir(dir)
+	return os.Setenv(dockerconfig.EnvOverrideConfigDir, dir)
+}
+
+// FromConfig returns the registries and the image rewrite rules of the config,
+// with the passwords read from the files or environment variables.
+func FromConfig(cfg *config.Container) (Registries, error) {
+	var rs Registries
+	for _, c := range cfg.ImageRewrites {
+		rule, err := NewRule(c.From, c.To)
+		if err != nil {
+			return Registries{}, fmt.Errorf("invalid image rewrite: %w", err)
+		}
+		rs.Rules = append(rs.Rules, rule)
+	}
+	for _, c := range cfg.Registries {
+		if c.Host == "" {
+			return Registries{}, fmt.Errorf("registry without host")
+		}
+		r := Registry{
+			Host:     c.Host,
+			Username: c.Username,
+			Mirror:   c.Mirror,
+		}
+		switch {
+		case c.PasswordFile != "":
+			content, err := os.ReadFile(c.PasswordFile)
+			if err != nil {
+				return Registries{}, fmt.Errorf("read password of registry %s: %w", c.Host, err)
+			}
+			r.Password = strings.TrimSpace(string(content))
+		case c.PasswordEnv != "":
+			v, ok := os.LookupEnv(c.PasswordEnv)
+			if !ok {
+				return Registries{}, fmt.Errorf("read password of registry %s: environment variable %s is not set", c.Host, c.PasswordEnv)
+			}
+			r.Password = v
+		}
+		rs.List = append(rs.List, r)
+	}
+	return rs, nil
+}
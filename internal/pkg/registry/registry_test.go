@@ -0,0 +1,125 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package registry
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitea.com/gitea/act_runner/internal/pkg/config"
+)
+
+func TestRegistries_Rewrite(t *testing.T) {
+	rule, err := NewRule("gitea.com/*/app:*", "mirror.local/gitea/*-app:*")
+	require.NoError(t, err)
+	rs := Registries{
+		List: []Registry{
+			{Host: "docker.io", Mirror: "mirror.local/dockerhub/"},
+			{Host: "ghcr.io", Mirror: "mirror.local/ghcr"},
+			{Host: "quay.io", Username: "someone"},
+		},
+		Rules: []Rule{rule},
+	}
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "node:18", want: "mirror.local/dockerhub/library/node:18"},
+		{image: "docker.io/owner/app", want: "mirror.local/dockerhub/owner/app"},
+		{image: "ghcr.io/owner/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", want: "mirror.local/ghcr/owner/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		{image: "quay.io/owner/app:1", want: "quay.io/owner/app:1"},
+		{image: "gitea.com/owner/app:1", want: "mirror.local/gitea/owner-app:1"},
+		{image: "gitea.com/owner/other:1", want: "gitea.com/owner/other:1"},
+		{image: "Invalid Image", want: "Invalid Image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, rs.Rewrite(tt.image))
+		})
+	}
+}
+
+func TestRegistries_Credentials(t *testing.T) {
+	rs := Registries{List: []Registry{
+		{Host: "index.docker.io", Username: "someone", Password: "secret"},
+	}}
+
+	username, password, ok := rs.Credentials("node:18")
+	assert.True(t, ok)
+	assert.Equal(t, "someone", username)
+	assert.Equal(t, "secret", password)
+
+	_, _, ok = rs.Credentials("ghcr.io/owner/app")
+	assert.False(t, ok)
+}
+
+func TestFromConfig(t *testing.T) {
+	rs, err := FromConfig(&config.Container{
+		Registries:    []config.Registry{{Host: "docker.io", Mirror: "mirror.local/dockerhub"}},
+		ImageRewrites: []config.ImageRewrite{{From: "docker.io/*", To: "mirror.local/*"}},
+	})
+	require.NoError(t, err)
+	// the rules take precedence over the mirrors
+	assert.Equal(t, "mirror.local/library/node:18", rs.Rewrite("node:18"))
+
+	_, err = FromConfig(&config.Container{
+		ImageRewrites: []config.ImageRewrite{{From: "docker.io/library/node", To: "mirror.local/*"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRegistries_WriteDockerConfig(t *testing.T) {
+	current := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(current, dockerconfig.ConfigFileName), []byte(`{
+	"auths": {"other.io": {"auth": "dXNlcjpwYXNz"}},
+	"credsStore": "missing-store",
+	"credHelpers": {"ghcr.io": "missing-helper", "quay.io": "missing-helper"}
+}`), 0o600))
+	t.Setenv(dockerconfig.EnvOverrideConfigDir, current)
+	dockerconfig.SetDir(current)
+	t.Cleanup(func() { dockerconfig.SetDir(current) })
+
+	rs := Registries{List: []Registry{
+		{Host: "docker.io", Username: "someone", Password: "secret"},
+		{Host: "ghcr.io", Username: "owner", Password: "token"},
+		{Host: "mirror.local"},
+	}}
+	dir := t.TempDir()
+	// the missing credentials stores and helpers are never run
+	require.NoError(t, rs.WriteDockerConfig(dir))
+	assert.Equal(t, dir, dockerconfig.Dir())
+	assert.Equal(t, dir, os.Getenv(dockerconfig.EnvOverrideConfigDir))
+
+	content, err := os.ReadFile(filepath.Join(dir, dockerconfig.ConfigFileName))
+	require.NoError(t, err)
+	var file struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredsStore  string                     `json:"credsStore"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+	}
+	require.NoError(t, json.Unmarshal(content, &file))
+	assert.ElementsMatch(t, []string{"other.io", dockerHubAuthKey, "ghcr.io"}, slices.Collect(maps.Keys(file.Auths)))
+	assert.Equal(t, "missing-store", file.CredsStore)
+	assert.Equal(t, map[string]string{
+		"ghcr.io":         "",
+		"quay.io":         "missing-helper",
+		dockerHubAuthKey:  "",
+		"index.docker.io": "",
+	}, file.CredHelpers)
+
+	loaded, err := dockerconfig.Load(dir)
+	require.NoError(t, err)
+	auth, err := loaded.GetAuthConfig("index.docker.io")
+	require.NoError(t, err)
+	assert.Equal(t, "someone", auth.Username)
+	assert.Equal(t, "secret", auth.Password)
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Annotation is a notice, warning or error written by a step with a workflow command,
+// like "::error file=app.go,line=1,col=5,endColumn=7,title=Syntax Error::Missing semicolon".
+type Annotation struct {
+	Level     string // Level is "notice", "warning" or "error".
+	Step      int    // Step is the index of the step, -1 means it isn't written by a step.
+	Message   string
+	Title     string
+	File      string
+	Line      string
+	EndLine   string
+	Col       string
+	EndColumn string
+}
+
+// annotationLevels are the commands of annotations, in the order of the summary.
+var annotationLevels = []string{"error", "warning", "notice"}
+
+// parseAnnotation parses the parameters and the value of an annotation command.
+func parseAnnotation(level, parameters, value string, step int) *Annotation {
+	a := &Annotation{
+		Level:   level,
+		Step:    step,
+		Message: unescapeData(value),
+	}
+	for _, param := range strings.Split(strings.TrimSpace(parameters), ",") {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		v = unescapeProperty(v)
+		switch strings.TrimSpace(k) {
+		case "title":
+			a.Title = v
+		case "file":
+			a.File = v
+		case "line":
+			a.Line = v
+		case "endLine":
+			a.EndLine = v
+		case "col":
+			a.Col = v
+		case "endColumn":
+			a.EndColumn = v
+		}
+	}
+	return a
+}
+
+// annotate adds an annotation written by the runner, not by a step, and writes it to the log.
+func (r *Reporter) annotate(level, format string, a ...interface{}) {
+	annotation := &Annotation{
+		Level:   level,
+		Step:    -1,
+		Message: fmt.Sprintf(format, a...),
+	}
+	r.annotations = append(r.annotations, annotation)
+	r.logf("%s", annotation)
+}
+
+// unescapeData unescapes the value of a workflow command, see https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func unescapeData(s string) string {
+	return strings.NewReplacer("%0D", "\r", "%0A", "\n", "%25", "%").Replace(s)
+}
+
+// unescapeProperty unescapes the value of a parameter of a workflow command.
+func unescapeProperty(s string) string {
+	return strings.NewReplacer("%0D", "\r", "%0A", "\n", "%3A", ":", "%2C", ",", "%25", "%").Replace(s)
+}
+
+// String returns the annotation as a log line, like "Error: app.go:1:5-7: Syntax Error: Missing semicolon".
+func (a *Annotation) String() string {
+	parts := []string{strings.ToUpper(a.Level[:1]) + a.Level[1:]}
+	if location := a.location(); location != "" {
+		parts = append(parts, location)
+	}
+	if a.Title != "" {
+		parts = append(parts, a.Title)
+	}
+	parts = append(parts, a.Message)
+	return strings.Join(parts, ": ")
+}
+
+// location returns where the annotation is, like "app.go:1-2" or "app.go:1:5-7".
+func (a *Annotation) location() string {
+	location := a.File
+	if a.Line == "" {
+		return location
+	}
+	location += ":" + a.Line
+	if a.EndLine != "" && a.EndLine != a.Line {
+		location += "-" + a.EndLine
+	}
+	if a.Col != "" {
+		location += ":" + a.Col
+		if a.EndColumn != "" && a.EndColumn != a.Col {
+			location += "-" + a.EndColumn
+		}
+	}
+	return location
+}
+
+// summarizeAnnotations returns the log rows summarizing the annotations with their counts, as a group.
+// It returns nothing if there is no annotation.
+func (r *Reporter) summarizeAnnotations() []*runnerv1.LogRow {
+	if len(r.annotations) == 0 {
+		return nil
+	}
+
+	counts := map[string]int{}
+	for _, a := range r.annotations {
+		counts[a.Level]++
+	}
+	var summary []string
+	for _, level := range annotationLevels {
+		summary = append(summary, plural(counts[level], level))
+	}
+
+	lines := []string{"::group::Annotations: " + strings.Join(summary, ", ")}
+	for _, level := range annotationLevels {
+		for _, a := range r.annotations {
+			if a.Level != level {
+				continue
+			}
+			if a.Step >= 0 {
+				lines = append(lines, fmt.Sprintf("%s: %s", r.stepName(a.Step), a))
+			} else {
+				lines = append(lines, a.String())
+			}
+		}
+	}
+	lines = append(lines, "::endgroup::")
+
+	rows := make([]*runnerv1.LogRow, 0, len(lines))
+	for _, line := range lines {
+		rows = append(rows, &runnerv1.LogRow{
+			Time:    timestamppb.Now(),
+			Content: r.masker.Replace(line),
+		})
+	}
+	return rows
+}
+
+// stepName returns the step like "step 2 (Build)".
+func (r *Reporter) stepName(step int) string {
+	if step < len(r.stepNames) {
+		return fmt.Sprintf("step %d (%s)", step+1, r.stepNames[step])
+	}
+	return fmt.Sprintf("step %d", step+1)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
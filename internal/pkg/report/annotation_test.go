@@ -0,0 +1,71 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotation_String(t *testing.T) {
+	tests := []struct {
+		parameters string
+		value      string
+		want       string
+	}{
+		{"", "Something happened", "Warning: Something happened"},
+		{" title=Heads up", "Something happened", "Warning: Heads up: Something happened"},
+		{" file=app.go", "Something happened", "Warning: app.go: Something happened"},
+		{" file=app.go,line=1,col=5,endColumn=7", "Missing semicolon", "Warning: app.go:1:5-7: Missing semicolon"},
+		{" file=app.go,line=3,endLine=3", "Unused", "Warning: app.go:3: Unused"},
+		{" file=a%2Cb.go,title=Key%3A value", "50%25%0Adone", "Warning: a,b.go: Key: value: 50%\ndone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAnnotation("warning", tt.parameters, tt.value, 0).String())
+		})
+	}
+}
+
+func TestReporter_summarizeAnnotations(t *testing.T) {
+	r := &Reporter{
+		masker:    newMasker("mysecret"),
+		stepNames: []string{"Checkout", "Build"},
+	}
+	assert.Empty(t, r.summarizeAnnotations())
+
+	for _, entry := range []*log.Entry{
+		{Message: "::warning file=app.go,line=1::Deprecated", Data: log.Fields{"stepNumber": 1}},
+		{Message: "::notice::Cache restored", Data: log.Fields{"stepNumber": 0}},
+		{Message: "::error title=Failed::token mysecret is invalid", Data: log.Fields{"stepNumber": 1}},
+		{Message: "::warning::Outside of steps"},
+	} {
+		r.parseLogRow(entry)
+	}
+
+	var got []string
+	for _, row := range r.summarizeAnnotations() {
+		got = append(got, row.Content)
+	}
+	assert.Equal(t, []string{
+		"::group::Annotations: 1 error, 2 warnings, 1 notice",
+		"step 2 (Build): Error: Failed: token *** is invalid",
+		"step 2 (Build): Warning: app.go:1: Deprecated",
+		"Warning: Outside of steps",
+		"step 1 (Checkout): Notice: Cache restored",
+		"::endgroup::",
+	}, got)
+}
+
+func TestReporter_parseLogRowAnnotation(t *testing.T) {
+	r := &Reporter{masker: newMasker()}
+	row := r.parseLogRow(&log.Entry{Message: "::error file=main.go,line=7::Oops", Data: log.Fields{"stepNumber": 2}})
+	assert.Equal(t, "Error: main.go:7: Oops", row.Content)
+	if assert.Len(t, r.annotations, 1) {
+		assert.Equal(t, 2, r.annotations[0].Step)
+		assert.Equal(t, "error", r.annotations[0].Level)
+	}
+}
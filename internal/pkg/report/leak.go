@@ -0,0 +1,182 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN[A-Z0-9 ]* PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END[A-Z0-9 ]* PRIVATE KEY( BLOCK)?-----`)
+	// privateKeyBody matches the lines between the beginning and the end of a private key:
+	// base64 of 64 characters at most, the headers of an encrypted key, or an empty line.
+	privateKeyBody = regexp.MustCompile(`^\s*([A-Za-z0-9+/]{1,64}={0,2}|(Proc-Type|DEK-Info|Comment): .*)?\s*$`)
+)
+
+// credentialPatterns are the formats of well-known credentials, only the "secret" group is redacted if there is one.
+// Access tokens of Gitea have no prefix, they look like commit hashes, so only its JWTs like the runtime token are detected.
+var credentialPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	// a private key is redacted until its end, or until the end of the line if it's printed in several lines
+	{"private key", regexp.MustCompile(privateKeyBegin.String() + `(.*?` + privateKeyEnd.String() + `|.*)`)},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,}`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`)},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_?secret_?access_?key[\s"':=]+(?P<secret>[A-Za-z0-9/+]{40})\b`)},
+}
+
+const (
+	// minLeakLength is the minimum length of a high-entropy token.
+	minLeakLength = 24
+	// leakEntropyMargin is how much lower than the expected entropy of a random token of the same length
+	// the entropy of a token may be, random tokens rarely fall below it while words joined by "_" or "-" mostly do.
+	leakEntropyMargin = 0.25
+)
+
+// tokenPattern matches the words which could be random tokens, "/" isn't included to skip paths.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+_=-]{24,}`)
+
+// findLeaks returns the ranges of content which look like credentials, with what they look like.
+func findLeaks(content string) ([]textRange, []string) {
+	var (
+		ranges []textRange
+		kinds  []string
+	)
+	for _, p := range credentialPatterns {
+		group := max(p.pattern.SubexpIndex("secret"), 0)
+		for _, loc := range p.pattern.FindAllStringSubmatchIndex(content, -1) {
+			ranges = append(ranges, textRange{loc[2*group], loc[2*group+1]})
+			kinds = append(kinds, p.kind)
+		}
+	}
+	for _, loc := range tokenPattern.FindAllStringIndex(content, -1) {
+		if isRandomToken(content[loc[0]:loc[1]]) && !overlaps(ranges, loc[0], loc[1]) {
+			ranges = append(ranges, textRange{loc[0], loc[1]})
+			kinds = append(kinds, "high-entropy token")
+		}
+	}
+	return ranges, kinds
+}
+
+// isRandomToken returns whether a word looks like a random token: it has upper and lower case letters and digits,
+// and its Shannon entropy is close to the one of a random token of the same length.
+// Hexadecimal strings like commit hashes and digests don't, since they have 16 different characters at most.
+func isRandomToken(s string) bool {
+	if len(s) < minLeakLength {
+		return false
+	}
+	var upper, lower, digit bool
+	counts := map[rune]int{}
+	for _, c := range s {
+		upper = upper || unicode.IsUpper(c)
+		lower = lower || unicode.IsLower(c)
+		digit = digit || unicode.IsDigit(c)
+		counts[c]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= randomEntropy(len(s))-leakEntropyMargin
+}
+
+// randomEntropy returns the expected Shannon entropy of a random string of n characters out of 64, like base64.
+// The count of every character follows the binomial distribution B(n, 1/64).
+func randomEntropy(n int) float64 {
+	const k = 64
+	lgN, _ := math.Lgamma(float64(n + 1))
+	entropy := 0.0
+	for c := 1; c <= n; c++ {
+		lgC, _ := math.Lgamma(float64(c + 1))
+		lgNC, _ := math.Lgamma(float64(n - c + 1))
+		prob := math.Exp(lgN - lgC - lgNC + float64(c)*math.Log(1.0/k) + float64(n-c)*math.Log(1-1.0/k))
+		q := float64(c) / float64(n)
+		entropy -= k * prob * q * math.Log2(q)
+	}
+	return entropy
+}
+
+func overlaps(ranges []textRange, start, end int) bool {
+	for _, r := range ranges {
+		if start < r.end && r.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLeakDetection sets whether to redact what looks like credentials from the log, besides the known secrets.
+func (r *Reporter) SetLeakDetection(enabled bool) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.detectLeaks = enabled
+}
+
+// redactLeaks redacts what looks like credentials from a log line written by step.
+// Every leak is recorded in the log of the runner, and as an annotation of the step, once for every kind.
+// The lines of a private key printed in several lines don't look like anything on their own,
+// so the lines after the beginning of a private key are redacted until its end, as long as they look like its body.
+// A beginning which isn't followed by a key, like a pattern of grep, only redacts its own line.
+func (r *Reporter) redactLeaks(content string, step int) string {
+	prefix := ""
+	if r.privateKeys[step] {
+		if loc := privateKeyEnd.FindStringIndex(content); loc != nil {
+			delete(r.privateKeys, step)
+			prefix, content = maskReplacement, content[loc[1]:]
+		} else if privateKeyBody.MatchString(content) {
+			return maskReplacement
+		} else {
+			delete(r.privateKeys, step)
+		}
+	}
+
+	ranges, kinds := findLeaks(content)
+	if begins := privateKeyBegin.FindAllStringIndex(content, -1); len(begins) > 0 {
+		if last := begins[len(begins)-1]; !privateKeyEnd.MatchString(content[last[1]:]) {
+			if r.privateKeys == nil {
+				r.privateKeys = map[int]bool{}
+			}
+			r.privateKeys[step] = true
+		}
+	}
+	if len(ranges) == 0 {
+		return prefix + content
+	}
+
+	for _, kind := range kinds {
+		log.WithFields(log.Fields{
+			"task": r.state.Id,
+			"step": step,
+			"kind": kind,
+		}).Warn("redacted a possible secret leak from the log")
+
+		key := fmt.Sprintf("%d/%s", step, kind)
+		if r.leaks == nil {
+			r.leaks = map[string]bool{}
+		}
+		if r.leaks[key] {
+			continue
+		}
+		r.leaks[key] = true
+		r.annotations = append(r.annotations, &Annotation{
+			Level:   "warning",
+			Step:    step,
+			Message: fmt.Sprintf("a possible %s has been redacted from the log, use secrets or add-mask to mask it", kind),
+		})
+	}
+	return prefix + replaceRanges(content, ranges, maskReplacement)
+}
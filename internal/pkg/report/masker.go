@@ -0,0 +1,238 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// minMaskLength is the minimum length of what is masked, shorter values like "1" or "on" would mask too much of the log.
+	minMaskLength = 3
+	// minEncodedMaskLength is the minimum length of a secret whose base64 forms are masked,
+	// the forms of shorter secrets are a few characters which appear in unrelated hashes and IDs.
+	minEncodedMaskLength = 8
+
+	maskReplacement = "***"
+)
+
+// masker replaces the secrets in log lines with "***".
+// Besides the secrets themselves, it masks every line of multi-line secrets, and their base64, URL-encoded and JSON-escaped forms.
+// It's nil-safe, a nil masker masks nothing.
+type masker struct {
+	mu       sync.Mutex
+	patterns map[string]struct{}
+	// matcher is rebuilt lazily when it's used after new patterns have been added,
+	// since a step could add a lot of masks in a row.
+	matcher *matcher
+	dirty   bool
+}
+
+func newMasker(secrets ...string) *masker {
+	m := &masker{patterns: map[string]struct{}{}}
+	for _, s := range secrets {
+		m.add(s)
+	}
+	return m
+}
+
+// add adds a secret to mask.
+func (m *masker) add(secret string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := []string{secret}
+	if strings.ContainsAny(secret, "\r\n") {
+		for _, line := range strings.FieldsFunc(secret, func(r rune) bool { return r == '\r' || r == '\n' }) {
+			values = append(values, strings.TrimSpace(line))
+		}
+	}
+	for _, v := range values {
+		for _, p := range maskForms(v) {
+			if len(p) < minMaskLength {
+				continue
+			}
+			if _, ok := m.patterns[p]; !ok {
+				m.patterns[p] = struct{}{}
+				m.dirty = true
+			}
+		}
+	}
+}
+
+// Replace replaces the masked values in s.
+func (m *masker) Replace(s string) string {
+	if m == nil {
+		return s
+	}
+	m.mu.Lock()
+	if m.dirty {
+		patterns := make([]string, 0, len(m.patterns))
+		for p := range m.patterns {
+			patterns = append(patterns, p)
+		}
+		m.matcher = newMatcher(patterns)
+		m.dirty = false
+	}
+	matcher := m.matcher
+	m.mu.Unlock()
+
+	if matcher == nil {
+		return s
+	}
+	return matcher.replace(s, maskReplacement)
+}
+
+// maskForms returns the forms of a value which could appear in the log.
+func maskForms(v string) []string {
+	forms := []string{v}
+
+	// the escaping of the JSON encoder, with and without escaping HTML characters
+	if content, err := json.Marshal(v); err == nil {
+		forms = append(forms, string(content[1:len(content)-1]))
+	}
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err == nil {
+		content := bytes.TrimSpace(buf.Bytes())
+		forms = append(forms, string(content[1:len(content)-1]))
+	}
+
+	forms = append(forms, url.QueryEscape(v), url.PathEscape(v))
+	forms = append(forms, base64Forms(v)...)
+	return forms
+}
+
+// base64Forms returns the base64 forms of a value, which don't depend on what is encoded along with it.
+// The encoding of a value depends on where it starts in a group of 3 bytes,
+// so there are 3 forms of it, without the characters which encode the bytes before or after it as well.
+func base64Forms(v string) []string {
+	if len(v) < minEncodedMaskLength {
+		return nil
+	}
+	var forms []string
+	for offset := 0; offset < 3; offset++ {
+		data := append(make([]byte, offset), v...)
+		start := (offset*8 + 5) / 6
+		end := len(data) * 8 / 6
+		if end-start < minMaskLength {
+			continue
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)[start:end]
+		forms = append(forms, encoded)
+		if urlEncoded := strings.NewReplacer("+", "-", "/", "_").Replace(encoded); urlEncoded != encoded {
+			forms = append(forms, urlEncoded)
+		}
+	}
+	return forms
+}
+
+// matcher finds several patterns in a string at once, with the Aho-Corasick algorithm.
+type matcher struct {
+	nodes []matcherNode
+}
+
+type matcherNode struct {
+	next map[byte]int
+	fail int
+	// length is the length of the longest pattern which ends at the node, including the ones of its failure nodes.
+	// The other patterns ending there are suffixes of it.
+	length int
+}
+
+func newMatcher(patterns []string) *matcher {
+	m := &matcher{nodes: []matcherNode{{next: map[byte]int{}}}}
+	for _, p := range patterns {
+		n := 0
+		for i := 0; i < len(p); i++ {
+			next, ok := m.nodes[n].next[p[i]]
+			if !ok {
+				next = len(m.nodes)
+				m.nodes = append(m.nodes, matcherNode{next: map[byte]int{}})
+				m.nodes[n].next[p[i]] = next
+			}
+			n = next
+		}
+		m.nodes[n].length = max(m.nodes[n].length, len(p))
+	}
+
+	// the failure node of a node is the node of its longest proper suffix, they are built in breadth-first order
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[n].next {
+			m.nodes[child].fail = m.step(m.nodes[n].fail, c)
+			m.nodes[child].length = max(m.nodes[child].length, m.nodes[m.nodes[child].fail].length)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// step returns the node after reading c at node n.
+func (m *matcher) step(n int, c byte) int {
+	for {
+		if next, ok := m.nodes[n].next[c]; ok {
+			return next
+		}
+		if n == 0 {
+			return 0
+		}
+		n = m.nodes[n].fail
+	}
+}
+
+// replace replaces every match in s with replacement.
+func (m *matcher) replace(s, replacement string) string {
+	var matches []textRange
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = m.step(n, s[i])
+		if length := m.nodes[n].length; length > 0 {
+			matches = append(matches, textRange{i + 1 - length, i + 1})
+		}
+	}
+	return replaceRanges(s, matches, replacement)
+}
+
+// textRange is the range s[start:end] of a string.
+type textRange struct{ start, end int }
+
+// replaceRanges replaces the ranges of s with replacement, overlapping and adjacent ranges are replaced as a whole.
+func replaceRanges(s string, ranges []textRange, replacement string) string {
+	if len(ranges) == 0 {
+		return s
+	}
+	// a match could start before the ones ending before it, if it's longer than them
+	slices.SortFunc(ranges, func(a, b textRange) int { return a.start - b.start })
+
+	var b strings.Builder
+	written := 0 // s[:written] has been written to b
+	start, end := ranges[0].start, ranges[0].end
+	for _, r := range ranges[1:] {
+		if r.start <= end {
+			end = max(end, r.end)
+			continue
+		}
+		b.WriteString(s[written:start])
+		b.WriteString(replacement)
+		written = end
+		start, end = r.start, r.end
+	}
+	b.WriteString(s[written:start])
+	b.WriteString(replacement)
+	b.WriteString(s[end:])
+	return b.String()
+}
@@ -0,0 +1,292 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"gitea.com/gitea/act_runner/internal/pkg/client"
+)
+
+const (
+	// outboxBatchRows is how many rows in the outbox are reported at most in a request.
+	outboxBatchRows = 1000
+
+	outboxRowsFile  = "rows"
+	outboxMetaFile  = "meta.json"
+	outboxStateFile = "state.json"
+)
+
+// outbox stores what hasn't been delivered to the Gitea instance of a task on disk.
+// Its directory is created when something is written to it, and contains:
+//   - rows: the log rows which have spilled from memory, one JSON per line
+//   - meta.json: where the undelivered rows start in rows
+//   - state.json: the final state and outputs of the task, it's written when the reporter gives up delivering them
+type outbox struct {
+	dir  string
+	meta outboxMeta
+}
+
+type outboxMeta struct {
+	LogOffset int   `json:"log_offset"` // LogOffset is the index of the first undelivered row in the log of the task.
+	ReadPos   int64 `json:"read_pos"`   // ReadPos is where the first undelivered row starts in rows.
+	Rows      int   `json:"rows"`       // Rows is how many rows are undelivered.
+}
+
+type outboxState struct {
+	State   json.RawMessage   `json:"state"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// openOutbox opens the outbox in dir, which may not exist.
+func openOutbox(dir string) (*outbox, error) {
+	o := &outbox{dir: dir}
+	content, err := os.ReadFile(filepath.Join(dir, outboxMetaFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return o, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &o.meta); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// appendRows appends rows to the outbox, the first of them is at index in the log of the task.
+func (o *outbox) appendRows(rows []*runnerv1.LogRow, index int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(o.dir, outboxRowsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, row := range rows {
+		content, err := protojson.Marshal(row)
+		if err != nil {
+			return err
+		}
+		_, _ = w.Write(content)
+		_ = w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if o.meta.Rows == 0 {
+		o.meta.LogOffset = index
+	}
+	o.meta.Rows += len(rows)
+	return o.saveMeta()
+}
+
+// readRows reads at most n undelivered rows.
+func (o *outbox) readRows(n int) ([]*runnerv1.LogRow, error) {
+	f, err := os.Open(filepath.Join(o.dir, outboxRowsFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(o.meta.ReadPos, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+	var rows []*runnerv1.LogRow
+	for len(rows) < n {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row := &runnerv1.LogRow{}
+		if err := protojson.Unmarshal(line, row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ack marks the first n undelivered rows as delivered.
+func (o *outbox) ack(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if n >= o.meta.Rows {
+		// everything has been delivered, start over with an empty file
+		o.meta = outboxMeta{LogOffset: o.meta.LogOffset + o.meta.Rows}
+		if err := os.Remove(filepath.Join(o.dir, outboxRowsFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return o.saveMeta()
+	}
+
+	f, err := os.Open(filepath.Join(o.dir, outboxRowsFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(o.meta.ReadPos, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	pos := o.meta.ReadPos
+	for i := 0; i < n; i++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		pos += int64(len(line))
+	}
+	o.meta.LogOffset += n
+	o.meta.ReadPos = pos
+	o.meta.Rows -= n
+	return o.saveMeta()
+}
+
+func (o *outbox) saveMeta() error {
+	content, err := json.Marshal(o.meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(o.dir, outboxMetaFile), content)
+}
+
+// saveState saves the final state and the unsent outputs of the task.
+func (o *outbox) saveState(state *runnerv1.TaskState, outputs map[string]string) error {
+	content, err := protojson.Marshal(state)
+	if err != nil {
+		return err
+	}
+	content, err = json.Marshal(outboxState{
+		State:   content,
+		Outputs: outputs,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(o.dir, outboxStateFile), content)
+}
+
+// loadState loads the final state and the unsent outputs of the task, the state is nil if it hasn't been saved.
+func (o *outbox) loadState() (*runnerv1.TaskState, map[string]string, error) {
+	content, err := os.ReadFile(filepath.Join(o.dir, outboxStateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var s outboxState
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, nil, err
+	}
+	state := &runnerv1.TaskState{}
+	if err := protojson.Unmarshal(s.State, state); err != nil {
+		return nil, nil, err
+	}
+	return state, s.Outputs, nil
+}
+
+// remove removes the outbox, after everything has been delivered.
+func (o *outbox) remove() error {
+	return os.RemoveAll(o.dir)
+}
+
+func writeFileAtomic(file string, content []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// DeliverOutboxes reports the logs and final states of the tasks in the outboxes in dir, which are removed after that.
+// The outboxes of the tasks for which running returns true are skipped, since their reporters are still using them.
+// An outbox without the final state is left by a task which didn't finish before the runner stopped.
+func DeliverOutboxes(ctx context.Context, client client.Client, dir string, running func(taskID int64) bool) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		taskID, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() || running(taskID) {
+			continue
+		}
+		if err := deliverOutbox(ctx, client, filepath.Join(dir, entry.Name()), taskID); err != nil {
+			errs = append(errs, fmt.Errorf("task %d: %w", taskID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func deliverOutbox(ctx context.Context, client client.Client, dir string, taskID int64) error {
+	o, err := openOutbox(dir)
+	if err != nil {
+		return err
+	}
+	state, outputs, err := o.loadState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		log.Warnf("task %d didn't finish before the runner stopped, its logs in the outbox are reported without its state", taskID)
+	}
+
+	r := &Reporter{
+		ctx:       ctx,
+		cancel:    func() {},
+		client:    client,
+		state:     state,
+		outbox:    o,
+		logOffset: o.meta.LogOffset,
+		spilled:   o.meta.Rows,
+	}
+	if r.state == nil {
+		r.state = &runnerv1.TaskState{Id: taskID}
+	}
+	for k, v := range outputs {
+		r.outputs.Store(k, v)
+	}
+
+	if err := r.reportLog(ctx, true); err != nil {
+		return err
+	}
+	if state != nil {
+		if err := r.reportState(ctx); err != nil {
+			return err
+		}
+	}
+	log.Infof("reported task %d from the outbox", taskID)
+	return o.remove()
+}
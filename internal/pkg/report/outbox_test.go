@@ -0,0 +1,173 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	connect_go "connectrpc.com/connect"
+	"github.com/avast/retry-go/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"gitea.com/gitea/act_runner/internal/pkg/client/mocks"
+)
+
+// fakeGitea records the logs and state reported, it's unreachable while down is true.
+type fakeGitea struct {
+	down   atomic.Bool
+	rows   []string
+	state  *runnerv1.TaskState
+	noMore bool
+}
+
+func (g *fakeGitea) client(t *testing.T) *mocks.Client {
+	client := mocks.NewClient(t)
+	client.On("UpdateLog", mock.Anything, mock.Anything).Return(func(_ context.Context, req *connect_go.Request[runnerv1.UpdateLogRequest]) (*connect_go.Response[runnerv1.UpdateLogResponse], error) {
+		if g.down.Load() {
+			return nil, errors.New("unreachable")
+		}
+		// like Gitea, the rows already received are ignored
+		ack := int64(len(g.rows))
+		if req.Msg.Index <= ack && req.Msg.Index+int64(len(req.Msg.Rows)) > ack {
+			for _, row := range req.Msg.Rows[ack-req.Msg.Index:] {
+				g.rows = append(g.rows, row.Content)
+			}
+		}
+		g.noMore = req.Msg.NoMore
+		return connect_go.NewResponse(&runnerv1.UpdateLogResponse{
+			AckIndex: int64(len(g.rows)),
+		}), nil
+	}).Maybe()
+	client.On("UpdateTask", mock.Anything, mock.Anything).Return(func(_ context.Context, req *connect_go.Request[runnerv1.UpdateTaskRequest]) (*connect_go.Response[runnerv1.UpdateTaskResponse], error) {
+		if g.down.Load() {
+			return nil, errors.New("unreachable")
+		}
+		g.state = req.Msg.State
+		return connect_go.NewResponse(&runnerv1.UpdateTaskResponse{}), nil
+	}).Maybe()
+	return client
+}
+
+func newOutboxReporter(t *testing.T, ctx context.Context, g *fakeGitea, dir string) *Reporter {
+	// don't wait long for Gitea when closing
+	old := closeRetryOptions
+	closeRetryOptions = []retry.Option{retry.Attempts(2), retry.Delay(time.Millisecond)}
+	t.Cleanup(func() { closeRetryOptions = old })
+
+	taskCtx, err := structpb.NewStruct(map[string]interface{}{})
+	require.NoError(t, err)
+	reporter := NewReporter(ctx, func() {}, g.client(t), &runnerv1.Task{
+		Id:      42,
+		Context: taskCtx,
+	})
+	reporter.EnableOutbox(dir, 3)
+	return reporter
+}
+
+func TestReporter_Outbox(t *testing.T) {
+	dir := t.TempDir()
+	g := &fakeGitea{}
+	g.down.Store(true)
+	reporter := newOutboxReporter(t, context.Background(), g, dir)
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		want = append(want, fmt.Sprintf("line %d", i))
+		reporter.Logf("line %d", i)
+		if i == 4 {
+			// the rows which have spilled are reported before the ones in memory
+			assert.Error(t, reporter.ReportLog(false))
+		}
+	}
+	assert.Equal(t, 8, reporter.spilled)
+	assert.Len(t, reporter.logRows, 2)
+	assert.FileExists(t, filepath.Join(dir, "42", outboxRowsFile))
+
+	g.down.Store(false)
+	require.NoError(t, reporter.ReportLog(false))
+	assert.Equal(t, want, g.rows)
+	assert.Equal(t, 10, reporter.logOffset)
+	assert.Equal(t, 0, reporter.spilled)
+	assert.Empty(t, reporter.logRows)
+	assert.NoFileExists(t, filepath.Join(dir, "42", outboxRowsFile))
+
+	require.NoError(t, reporter.Close("done"))
+	assert.True(t, g.noMore)
+	assert.Equal(t, runnerv1.Result_RESULT_FAILURE, g.state.Result)
+	assert.NoDirExists(t, filepath.Join(dir, "42"))
+}
+
+func TestReporter_Outbox_StepLogs(t *testing.T) {
+	g := &fakeGitea{}
+	g.down.Store(true)
+	reporter := newOutboxReporter(t, context.Background(), g, t.TempDir())
+	reporter.ResetSteps(3)
+
+	for i := 0; i < 4; i++ {
+		reporter.Logf("line %d", i)
+	}
+	require.Equal(t, 4, reporter.spilled)
+
+	fire := func(step int, message string) {
+		require.NoError(t, reporter.Fire(&log.Entry{Message: message, Data: log.Fields{"stage": "Main", "stepNumber": step, "raw_output": true}}))
+	}
+	fire(0, "step 0 line 0")
+	fire(0, "step 0 line 1")
+	// the rows of the step spill in the middle of it
+	fire(1, "step 1 line 0")
+	fire(1, "step 1 line 1")
+	fire(1, "step 1 line 2")
+	require.Equal(t, 8, reporter.spilled)
+	// a step without output starts where the log ends
+	require.NoError(t, reporter.Fire(&log.Entry{Message: "done", Data: log.Fields{"stage": "Main", "stepNumber": 2, "stepResult": "success"}}))
+
+	steps := reporter.state.Steps
+	assert.Equal(t, []int64{4, 6, 9}, []int64{steps[0].LogIndex, steps[1].LogIndex, steps[2].LogIndex})
+	assert.Equal(t, []int64{2, 3, 0}, []int64{steps[0].LogLength, steps[1].LogLength, steps[2].LogLength})
+
+	g.down.Store(false)
+	require.NoError(t, reporter.Close(""))
+	assert.Equal(t, "step 0 line 0", g.rows[steps[0].LogIndex])
+	assert.Equal(t, "step 1 line 0", g.rows[steps[1].LogIndex])
+}
+
+func TestReporter_OutboxDeliveredLater(t *testing.T) {
+	dir := t.TempDir()
+	g := &fakeGitea{}
+	g.down.Store(true)
+
+	// the task has timed out, and Gitea is unreachable
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reporter := newOutboxReporter(t, ctx, g, dir)
+	reporter.Logf("line 0")
+	reporter.Logf("line 1")
+	require.NoError(t, reporter.Close("timed out"))
+	assert.FileExists(t, filepath.Join(dir, "42", outboxStateFile))
+
+	// a task still running is skipped
+	g.down.Store(false)
+	require.NoError(t, DeliverOutboxes(context.Background(), g.client(t), dir, func(int64) bool { return true }))
+	assert.Empty(t, g.rows)
+
+	require.NoError(t, DeliverOutboxes(context.Background(), g.client(t), dir, func(int64) bool { return false }))
+	assert.Equal(t, []string{"line 0", "line 1", "timed out"}, g.rows)
+	assert.True(t, g.noMore)
+	assert.Equal(t, int64(42), g.state.Id)
+	assert.Equal(t, runnerv1.Result_RESULT_FAILURE, g.state.Result)
+	_, err := os.Stat(filepath.Join(dir, "42"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -5,8 +5,11 @@ package report
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +22,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"gitea.com/gitea/act_runner/internal/pkg/client"
+	"gitea.com/gitea/act_runner/internal/pkg/logarchive"
 )
 
 type Reporter struct {
@@ -31,8 +35,21 @@ type Reporter struct {
 
 	logOffset   int
 	logRows     []*runnerv1.LogRow
-	logReplacer *strings.Replacer
-	oldnew      []string
+	masker      *masker
+	archive     *logarchive.Log // archive receives a copy of the log rows, it could be nil.
+	stepNames   []string
+	annotations []*Annotation
+
+	summaryReader  SummaryReader
+	summaryMaxSize int64
+	summaries      []stepSummary
+
+	// outbox stores the log rows which have spilled from memory, and what can't be reported when closing, it could be nil.
+	// The spilled rows come right after logOffset, and the rows in memory come after them.
+	outbox        *outbox
+	spilled       int
+	spillFailed   bool
+	maxMemoryRows int
 
 	state   *runnerv1.TaskState
 	stateMu sync.RWMutex
@@ -40,26 +57,45 @@ type Reporter struct {
 
 	debugOutputEnabled  bool
 	stopCommandEndToken string
+
+	outputSecrets OutputSecrets
+	// outputsSet indicates whether the outputs of the job have been set.
+	// With OutputSecretsFail, the result of the job isn't reported before, since the outputs could still fail the job,
+	// and Gitea ignores the updates of a task which is done.
+	outputsSet bool
+
+	detectLeaks bool
+	leaks       map[string]bool // leaks are the kinds of the leaks detected in every step, like "2/JWT".
+	privateKeys map[int]bool    // privateKeys are the steps in the middle of printing a private key.
 }
 
+// OutputSecrets is what to do with a job output containing a secret,
+// since the jobs depending on the job could print it without masking it.
+type OutputSecrets string
+
+const (
+	OutputSecretsRedact OutputSecrets = "redact" // OutputSecretsRedact replaces the secret in the output with "***", it's the default.
+	OutputSecretsDrop   OutputSecrets = "drop"   // OutputSecretsDrop drops the output.
+	OutputSecretsFail   OutputSecrets = "fail"   // OutputSecretsFail drops the output and fails the job.
+)
+
 func NewReporter(ctx context.Context, cancel context.CancelFunc, client client.Client, task *runnerv1.Task) *Reporter {
-	var oldnew []string
+	masker := newMasker()
 	if v := task.Context.Fields["token"].GetStringValue(); v != "" {
-		oldnew = append(oldnew, v, "***")
+		masker.add(v)
 	}
 	if v := task.Context.Fields["gitea_runtime_token"].GetStringValue(); v != "" {
-		oldnew = append(oldnew, v, "***")
+		masker.add(v)
 	}
 	for _, v := range task.Secrets {
-		oldnew = append(oldnew, v, "***")
+		masker.add(v)
 	}
 
 	rv := &Reporter{
-		ctx:         ctx,
-		cancel:      cancel,
-		client:      client,
-		oldnew:      oldnew,
-		logReplacer: strings.NewReplacer(oldnew...),
+		ctx:    ctx,
+		cancel: cancel,
+		client: client,
+		masker: masker,
 		state: &runnerv1.TaskState{
 			Id: task.Id,
 		},
@@ -72,6 +108,21 @@ func NewReporter(ctx context.Context, cancel context.CancelFunc, client client.C
 	return rv
 }
 
+// SetArchive sets the local archive which the log rows are written to as well, after masking.
+func (r *Reporter) SetArchive(archive *logarchive.Log) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.archive = archive
+}
+
+// SetStepNames sets the names of the steps, which describe the steps in the annotation summary and the archive.
+func (r *Reporter) SetStepNames(names []string) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.stepNames = names
+	r.archive.SetStepNames(names)
+}
+
 func (r *Reporter) ResetSteps(l int) {
 	r.stateMu.Lock()
 	defer r.stateMu.Unlock()
@@ -86,14 +137,37 @@ func (r *Reporter) Levels() []log.Level {
 	return log.AllLevels
 }
 
-func appendIfNotNil[T any](s []*T, v *T) []*T {
-	if v != nil {
-		return append(s, v)
+// addLogRow appends a log row to the ones to report, and writes it to the archive. A nil row is ignored.
+// The rows in memory spill to the outbox if there are too many of them.
+func (r *Reporter) addLogRow(row *runnerv1.LogRow) {
+	if row == nil {
+		return
+	}
+	r.logRows = append(r.logRows, row)
+	r.archive.WriteRow(row)
+
+	if r.outbox != nil && r.maxMemoryRows > 0 && len(r.logRows) > r.maxMemoryRows {
+		// the rows are kept in memory if it fails, it will be tried again with the next row
+		err := r.spill()
+		if err != nil && !r.spillFailed {
+			log.WithError(err).Errorf("failed to spill the log rows of task %d to the outbox", r.state.Id)
+		}
+		r.spillFailed = err != nil
 	}
-	return s
 }
 
 func (r *Reporter) Fire(entry *log.Entry) error {
+	if entry.Data["stage"] == "Main" {
+		if step, ok := entry.Data["stepNumber"].(int); ok {
+			if v, ok := entry.Data["stepResult"]; ok {
+				if result, ok := r.parseResult(v); ok && result != runnerv1.Result_RESULT_SKIPPED {
+					// a skipped step doesn't truncate the summary file of the previous step
+					r.collectSummary(step)
+				}
+			}
+		}
+	}
+
 	r.stateMu.Lock()
 	defer r.stateMu.Unlock()
 
@@ -111,6 +185,7 @@ func (r *Reporter) Fire(entry *log.Entry) error {
 			if jobResult, ok := r.parseResult(v); ok {
 				r.state.Result = jobResult
 				r.state.StoppedAt = timestamppb.New(timestamp)
+				r.archive.JobStopped(jobResult, timestamp)
 				for _, s := range r.state.Steps {
 					if s.Result == runnerv1.Result_RESULT_UNSPECIFIED {
 						s.Result = runnerv1.Result_RESULT_CANCELLED
@@ -122,7 +197,7 @@ func (r *Reporter) Fire(entry *log.Entry) error {
 			}
 		}
 		if !r.duringSteps() {
-			r.logRows = appendIfNotNil(r.logRows, r.parseLogRow(entry))
+			r.addLogRow(r.parseLogRow(entry))
 		}
 		return nil
 	}
@@ -135,34 +210,36 @@ func (r *Reporter) Fire(entry *log.Entry) error {
 	}
 	if step == nil {
 		if !r.duringSteps() {
-			r.logRows = appendIfNotNil(r.logRows, r.parseLogRow(entry))
+			r.addLogRow(r.parseLogRow(entry))
 		}
 		return nil
 	}
 
 	if step.StartedAt == nil {
 		step.StartedAt = timestamppb.New(timestamp)
+		r.archive.StepStarted(step.Id, timestamp)
 	}
 	if v, ok := entry.Data["raw_output"]; ok {
 		if rawOutput, ok := v.(bool); ok && rawOutput {
 			if row := r.parseLogRow(entry); row != nil {
 				if step.LogLength == 0 {
-					step.LogIndex = int64(r.logOffset + len(r.logRows))
+					step.LogIndex = int64(r.logOffset + r.spilled + len(r.logRows))
 				}
 				step.LogLength++
-				r.logRows = append(r.logRows, row)
+				r.addLogRow(row)
 			}
 		}
 	} else if !r.duringSteps() {
-		r.logRows = appendIfNotNil(r.logRows, r.parseLogRow(entry))
+		r.addLogRow(r.parseLogRow(entry))
 	}
 	if v, ok := entry.Data["stepResult"]; ok {
 		if stepResult, ok := r.parseResult(v); ok {
 			if step.LogLength == 0 {
-				step.LogIndex = int64(r.logOffset + len(r.logRows))
+				step.LogIndex = int64(r.logOffset + r.spilled + len(r.logRows))
 			}
 			step.Result = stepResult
 			step.StoppedAt = timestamppb.New(timestamp)
+			r.archive.StepStopped(step.Id, stepResult, timestamp)
 		}
 	}
 
@@ -192,16 +269,24 @@ func (r *Reporter) Logf(format string, a ...interface{}) {
 
 func (r *Reporter) logf(format string, a ...interface{}) {
 	if !r.duringSteps() {
-		r.logRows = append(r.logRows, &runnerv1.LogRow{
+		r.addLogRow(&runnerv1.LogRow{
 			Time:    timestamppb.Now(),
 			Content: fmt.Sprintf(format, a...),
 		})
 	}
 }
 
+// SetOutputSecrets sets what to do with the job outputs containing secrets.
+func (r *Reporter) SetOutputSecrets(action OutputSecrets) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.outputSecrets = action
+}
+
 func (r *Reporter) SetOutputs(outputs map[string]string) {
 	r.stateMu.Lock()
 	defer r.stateMu.Unlock()
+	r.outputsSet = true
 
 	for k, v := range outputs {
 		if len(k) > 255 {
@@ -215,14 +300,49 @@ func (r *Reporter) SetOutputs(outputs map[string]string) {
 		if _, ok := r.outputs.Load(k); ok {
 			continue
 		}
+		if masked := r.masker.Replace(v); masked != v {
+			switch r.outputSecrets {
+			case OutputSecretsDrop:
+				r.annotate("warning", "output %q contains a secret, it's dropped", k)
+				continue
+			case OutputSecretsFail:
+				r.annotate("error", "output %q contains a secret, it's dropped and the job fails", k)
+				if r.state.Result == runnerv1.Result_RESULT_SUCCESS {
+					r.state.Result = runnerv1.Result_RESULT_FAILURE
+				}
+				continue
+			default:
+				r.annotate("warning", "output %q contains a secret, it's redacted", k)
+				v = masked
+			}
+		}
 		r.outputs.Store(k, v)
 	}
 }
 
+// Result returns the result of the task reported so far.
+func (r *Reporter) Result() runnerv1.Result {
+	r.stateMu.RLock()
+	defer r.stateMu.RUnlock()
+
+	return r.state.Result
+}
+
+// closeRetryOptions are the options of retrying the final report when closing.
+var closeRetryOptions []retry.Option
+
 func (r *Reporter) Close(lastWords string) error {
 	r.closed = true
 
 	r.stateMu.Lock()
+	// the outputs won't be set anymore
+	r.outputsSet = true
+	for _, row := range r.summarizeAnnotations() {
+		r.addLogRow(row)
+	}
+	for _, row := range r.summarizeSteps() {
+		r.addLogRow(row)
+	}
 	if r.state.Result == runnerv1.Result_RESULT_UNSPECIFIED {
 		if lastWords == "" {
 			lastWords = "Early termination"
@@ -233,68 +353,171 @@ func (r *Reporter) Close(lastWords string) error {
 			}
 		}
 		r.state.Result = runnerv1.Result_RESULT_FAILURE
-		r.logRows = append(r.logRows, &runnerv1.LogRow{
+		r.addLogRow(&runnerv1.LogRow{
 			Time:    timestamppb.Now(),
 			Content: lastWords,
 		})
 		r.state.StoppedAt = timestamppb.Now()
+		r.archive.JobStopped(r.state.Result, r.state.StoppedAt.AsTime())
 	} else if lastWords != "" {
-		r.logRows = append(r.logRows, &runnerv1.LogRow{
+		r.addLogRow(&runnerv1.LogRow{
 			Time:    timestamppb.Now(),
 			Content: lastWords,
 		})
 	}
 	r.stateMu.Unlock()
 
-	return retry.Do(func() error {
-		if err := r.ReportLog(true); err != nil {
+	// the final logs and state are still reported after the task has been cancelled or has timed out
+	ctx := context.WithoutCancel(r.ctx)
+	err := retry.Do(func() error {
+		if err := r.reportLog(ctx, true); err != nil {
 			return err
 		}
-		return r.ReportState()
-	}, retry.Context(r.ctx))
+		return r.reportState(ctx)
+	}, append(closeRetryOptions, retry.Context(ctx))...)
+	if r.outbox == nil {
+		return err
+	}
+	if err != nil {
+		if saveErr := r.saveOutbox(); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		log.WithError(err).Warnf("failed to report task %d, it will be reported from the outbox %s later", r.state.Id, r.outbox.dir)
+		return nil
+	}
+	return r.outbox.remove()
 }
 
-func (r *Reporter) ReportLog(noMore bool) error {
-	r.clientM.Lock()
-	defer r.clientM.Unlock()
+// EnableOutbox makes the log rows spill to an outbox in dir when there are more than maxMemoryRows in memory,
+// and makes the final logs and state saved to it if they can't be reported when closing.
+// The outbox is delivered by DeliverOutboxes later.
+func (r *Reporter) EnableOutbox(dir string, maxMemoryRows int) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
 
-	r.stateMu.RLock()
-	rows := r.logRows
-	r.stateMu.RUnlock()
+	r.outbox = &outbox{dir: filepath.Join(dir, strconv.FormatInt(r.state.Id, 10))}
+	r.maxMemoryRows = maxMemoryRows
+}
 
-	resp, err := r.client.UpdateLog(r.ctx, connect.NewRequest(&runnerv1.UpdateLogRequest{
-		TaskId: r.state.Id,
-		Index:  int64(r.logOffset),
-		Rows:   rows,
-		NoMore: noMore,
-	}))
-	if err != nil {
+// spill moves the log rows in memory to the outbox.
+func (r *Reporter) spill() error {
+	if err := r.outbox.appendRows(r.logRows, r.logOffset+r.spilled); err != nil {
 		return err
 	}
+	r.spilled += len(r.logRows)
+	r.logRows = nil
+	return nil
+}
+
+// saveOutbox saves the undelivered log rows and the final state to the outbox.
+func (r *Reporter) saveOutbox() error {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
 
-	ack := int(resp.Msg.AckIndex)
-	if ack < r.logOffset {
-		return fmt.Errorf("submitted logs are lost")
+	if err := r.spill(); err != nil {
+		return err
 	}
+	outputs := map[string]string{}
+	r.outputs.Range(func(k, v interface{}) bool {
+		if val, ok := v.(string); ok {
+			outputs[k.(string)] = val
+		}
+		return true
+	})
+	return r.outbox.saveState(r.state, outputs)
+}
 
-	r.stateMu.Lock()
-	r.logRows = r.logRows[ack-r.logOffset:]
-	r.logOffset = ack
-	r.stateMu.Unlock()
+func (r *Reporter) ReportLog(noMore bool) error {
+	return r.reportLog(r.ctx, noMore)
+}
+
+func (r *Reporter) reportLog(ctx context.Context, noMore bool) error {
+	r.clientM.Lock()
+	defer r.clientM.Unlock()
+
+	for {
+		// the rows in the outbox are older than the ones in memory, so they are reported first
+		r.stateMu.RLock()
+		offset := r.logOffset
+		spilled := r.spilled > 0
+		var rows []*runnerv1.LogRow
+		var err error
+		if spilled {
+			rows, err = r.outbox.readRows(min(r.spilled, outboxBatchRows))
+		} else {
+			rows = r.logRows
+		}
+		r.stateMu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("failed to read the outbox: %w", err)
+		}
+
+		resp, err := r.client.UpdateLog(ctx, connect.NewRequest(&runnerv1.UpdateLogRequest{
+			TaskId: r.state.Id,
+			Index:  int64(offset),
+			Rows:   rows,
+			NoMore: noMore && !spilled,
+		}))
+		if err != nil {
+			return err
+		}
+
+		ack := int(resp.Msg.AckIndex)
+		if ack < offset {
+			return fmt.Errorf("submitted logs are lost")
+		}
 
-	if noMore && ack < r.logOffset+len(rows) {
-		return fmt.Errorf("not all logs are submitted")
+		r.stateMu.Lock()
+		err = r.ackLogRows(ack)
+		r.stateMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to update the outbox: %w", err)
+		}
+
+		if ack < offset+len(rows) {
+			if spilled || noMore {
+				return fmt.Errorf("not all logs are submitted")
+			}
+			return nil
+		}
+		if !spilled {
+			return nil
+		}
 	}
+}
 
+// ackLogRows drops the log rows before ack, which have been reported, from the outbox first and then from memory.
+func (r *Reporter) ackLogRows(ack int) error {
+	n := ack - r.logOffset
+	if n <= 0 {
+		return nil
+	}
+	if spilled := min(n, r.spilled); spilled > 0 {
+		if err := r.outbox.ack(spilled); err != nil {
+			return err
+		}
+		r.spilled -= spilled
+		n -= spilled
+	}
+	r.logRows = r.logRows[min(n, len(r.logRows)):]
+	r.logOffset = ack
 	return nil
 }
 
 func (r *Reporter) ReportState() error {
+	return r.reportState(r.ctx)
+}
+
+func (r *Reporter) reportState(ctx context.Context) error {
 	r.clientM.Lock()
 	defer r.clientM.Unlock()
 
 	r.stateMu.RLock()
 	state := proto.Clone(r.state).(*runnerv1.TaskState)
+	if r.outputSecrets == OutputSecretsFail && !r.outputsSet {
+		state.Result = runnerv1.Result_RESULT_UNSPECIFIED
+		state.StoppedAt = nil
+	}
 	r.stateMu.RUnlock()
 
 	outputs := make(map[string]string)
@@ -305,7 +528,7 @@ func (r *Reporter) ReportState() error {
 		return true
 	})
 
-	resp, err := r.client.UpdateTask(r.ctx, connect.NewRequest(&runnerv1.UpdateTaskRequest{
+	resp, err := r.client.UpdateTask(ctx, connect.NewRequest(&runnerv1.UpdateTaskRequest{
 		State:   state,
 		Outputs: outputs,
 	}))
@@ -367,7 +590,7 @@ func (r *Reporter) parseResult(result interface{}) (runnerv1.Result, bool) {
 
 var cmdRegex = regexp.MustCompile(`^::([^ :]+)( .*)?::(.*)$`)
 
-func (r *Reporter) handleCommand(originalContent, command, parameters, value string) *string {
+func (r *Reporter) handleCommand(originalContent, command, parameters, value string, step int) *string {
 	if r.stopCommandEndToken != "" && command != r.stopCommandEndToken {
 		return &originalContent
 	}
@@ -382,15 +605,11 @@ func (r *Reporter) handleCommand(originalContent, command, parameters, value str
 		}
 		return nil
 
-	case "notice":
-		// Not implemented yet, so just return the original content.
-		return &originalContent
-	case "warning":
-		// Not implemented yet, so just return the original content.
-		return &originalContent
-	case "error":
-		// Not implemented yet, so just return the original content.
-		return &originalContent
+	case "notice", "warning", "error":
+		a := parseAnnotation(command, parameters, value, step)
+		r.annotations = append(r.annotations, a)
+		content := a.String()
+		return &content
 	case "group":
 		// Returning the original content, because I think the frontend
 		// will use it when rendering the output.
@@ -411,16 +630,24 @@ func (r *Reporter) handleCommand(originalContent, command, parameters, value str
 func (r *Reporter) parseLogRow(entry *log.Entry) *runnerv1.LogRow {
 	content := strings.TrimRightFunc(entry.Message, func(r rune) bool { return r == '\r' || r == '\n' })
 
+	step := -1
+	if v, ok := entry.Data["stepNumber"].(int); ok {
+		step = v
+	}
+
 	matches := cmdRegex.FindStringSubmatch(content)
 	if matches != nil {
-		if output := r.handleCommand(content, matches[1], matches[2], matches[3]); output != nil {
+		if output := r.handleCommand(content, matches[1], matches[2], matches[3], step); output != nil {
 			content = *output
 		} else {
 			return nil
 		}
 	}
 
-	content = r.logReplacer.Replace(content)
+	content = r.masker.Replace(content)
+	if r.detectLeaks {
+		content = r.redactLeaks(content, step)
+	}
 
 	return &runnerv1.LogRow{
 		Time:    timestamppb.New(entry.Time),
@@ -429,6 +656,5 @@ func (r *Reporter) parseLogRow(entry *log.Entry) *runnerv1.LogRow {
 }
 
 func (r *Reporter) addMask(msg string) {
-	r.oldnew = append(r.oldnew, msg, "***")
-	r.logReplacer = strings.NewReplacer(r.oldnew...)
+	r.masker.add(msg)
 }
@@ -5,6 +5,8 @@ package report
 
 import (
 	"context"
+	"encoding/base64"
+	"os"
 	"strings"
 	"testing"
 
@@ -17,6 +19,7 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"gitea.com/gitea/act_runner/internal/pkg/client/mocks"
+	"gitea.com/gitea/act_runner/internal/pkg/logarchive"
 )
 
 func TestReporter_parseLogRow(t *testing.T) {
@@ -68,7 +71,7 @@ func TestReporter_parseLogRow(t *testing.T) {
 				"::notice file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
 			},
 			[]string{
-				"::notice file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
+				"Notice: file.name:42-48: Cool Title: Gosh, that's not going to work",
 			},
 		},
 		{
@@ -77,7 +80,7 @@ func TestReporter_parseLogRow(t *testing.T) {
 				"::warning file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
 			},
 			[]string{
-				"::warning file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
+				"Warning: file.name:42-48: Cool Title: Gosh, that's not going to work",
 			},
 		},
 		{
@@ -86,7 +89,7 @@ func TestReporter_parseLogRow(t *testing.T) {
 				"::error file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
 			},
 			[]string{
-				"::error file=file.name,line=42,endLine=48,title=Cool Title::Gosh, that's not going to work",
+				"Error: file.name:42-48: Cool Title: Gosh, that's not going to work",
 			},
 		},
 		{
@@ -138,7 +141,7 @@ func TestReporter_parseLogRow(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := &Reporter{
-				logReplacer:        strings.NewReplacer(),
+				masker:             newMasker(),
 				debugOutputEnabled: tt.debugOutputEnabled,
 			}
 			for idx, arg := range tt.args {
@@ -195,3 +198,148 @@ func TestReporter_Fire(t *testing.T) {
 		assert.Equal(t, int64(3), reporter.state.Steps[0].LogLength)
 	})
 }
+
+func TestReporter_Archive(t *testing.T) {
+	client := mocks.NewClient(t)
+	client.On("UpdateLog", mock.Anything, mock.Anything).Return(func(_ context.Context, req *connect_go.Request[runnerv1.UpdateLogRequest]) (*connect_go.Response[runnerv1.UpdateLogResponse], error) {
+		return connect_go.NewResponse(&runnerv1.UpdateLogResponse{
+			AckIndex: req.Msg.Index + int64(len(req.Msg.Rows)),
+		}), nil
+	})
+	client.On("UpdateTask", mock.Anything, mock.Anything).Return(func(_ context.Context, _ *connect_go.Request[runnerv1.UpdateTaskRequest]) (*connect_go.Response[runnerv1.UpdateTaskResponse], error) {
+		return connect_go.NewResponse(&runnerv1.UpdateTaskResponse{}), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	taskCtx, err := structpb.NewStruct(map[string]interface{}{})
+	require.NoError(t, err)
+	reporter := NewReporter(ctx, cancel, client, &runnerv1.Task{
+		Context: taskCtx,
+		Secrets: map[string]string{"TOKEN": "mysecret"},
+	})
+
+	archive, err := (&logarchive.Archive{Dir: t.TempDir()}).Create("owner/repo", "1", 2)
+	require.NoError(t, err)
+	reporter.SetArchive(archive)
+	reporter.ResetSteps(1)
+	reporter.SetStepNames([]string{"Build"})
+
+	reporter.Logf("received task")
+	dataStep0 := map[string]interface{}{
+		"stage":      "Main",
+		"stepNumber": 0,
+		"raw_output": true,
+	}
+	assert.NoError(t, reporter.Fire(&log.Entry{Message: "token is mysecret", Data: dataStep0}))
+	assert.NoError(t, reporter.Fire(&log.Entry{Message: "done", Data: map[string]interface{}{
+		"stage":      "Main",
+		"stepNumber": 0,
+		"stepResult": "success",
+	}}))
+	assert.NoError(t, reporter.Close("cancelled"))
+	require.NoError(t, archive.Close())
+
+	content, err := os.ReadFile(archive.Path())
+	require.NoError(t, err)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		// trim the time
+		_, line, _ = strings.Cut(line, " ")
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{
+		"received task",
+		"=== step 1 (Build) started",
+		"token is ***",
+		"=== step 1 (Build) finished: success",
+		"cancelled",
+		"=== job finished: failure",
+	}, lines)
+}
+
+func TestReporter_SetOutputs(t *testing.T) {
+	outputs := map[string]string{
+		"version": "1.2.3",
+		"leaked":  "token=mysecret",
+		"encoded": base64.StdEncoding.EncodeToString([]byte("mysecret")),
+	}
+	newReporter := func(action OutputSecrets) *Reporter {
+		r := &Reporter{
+			masker: newMasker("mysecret"),
+			state:  &runnerv1.TaskState{Result: runnerv1.Result_RESULT_SUCCESS},
+		}
+		r.SetOutputSecrets(action)
+		r.SetOutputs(outputs)
+		return r
+	}
+	loadOutputs := func(r *Reporter) map[string]string {
+		got := map[string]string{}
+		r.outputs.Range(func(k, v interface{}) bool {
+			got[k.(string)] = v.(string)
+			return true
+		})
+		return got
+	}
+
+	t.Run("redact", func(t *testing.T) {
+		r := newReporter("")
+		assert.Equal(t, map[string]string{
+			"version": "1.2.3",
+			"leaked":  "token=***",
+			"encoded": "***Q=",
+		}, loadOutputs(r))
+		assert.Equal(t, runnerv1.Result_RESULT_SUCCESS, r.state.Result)
+		if assert.Len(t, r.annotations, 2) {
+			assert.Equal(t, "warning", r.annotations[0].Level)
+		}
+		assert.Len(t, r.logRows, 2)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		r := newReporter(OutputSecretsDrop)
+		assert.Equal(t, map[string]string{"version": "1.2.3"}, loadOutputs(r))
+		assert.Equal(t, runnerv1.Result_RESULT_SUCCESS, r.state.Result)
+		assert.Len(t, r.annotations, 2)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		r := newReporter(OutputSecretsFail)
+		assert.Equal(t, map[string]string{"version": "1.2.3"}, loadOutputs(r))
+		assert.Equal(t, runnerv1.Result_RESULT_FAILURE, r.state.Result)
+		if assert.Len(t, r.annotations, 2) {
+			assert.Equal(t, "error", r.annotations[0].Level)
+		}
+	})
+}
+
+func TestReporter_SetOutputs_resultHeld(t *testing.T) {
+	var results []runnerv1.Result
+	client := mocks.NewClient(t)
+	client.On("UpdateLog", mock.Anything, mock.Anything).Return(func(_ context.Context, req *connect_go.Request[runnerv1.UpdateLogRequest]) (*connect_go.Response[runnerv1.UpdateLogResponse], error) {
+		return connect_go.NewResponse(&runnerv1.UpdateLogResponse{
+			AckIndex: req.Msg.Index + int64(len(req.Msg.Rows)),
+		}), nil
+	})
+	client.On("UpdateTask", mock.Anything, mock.Anything).Return(func(_ context.Context, req *connect_go.Request[runnerv1.UpdateTaskRequest]) (*connect_go.Response[runnerv1.UpdateTaskResponse], error) {
+		results = append(results, req.Msg.State.Result)
+		return connect_go.NewResponse(&runnerv1.UpdateTaskResponse{}), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	taskCtx, err := structpb.NewStruct(map[string]interface{}{})
+	require.NoError(t, err)
+	reporter := NewReporter(ctx, cancel, client, &runnerv1.Task{
+		Context: taskCtx,
+		Secrets: map[string]string{"TOKEN": "mysecret"},
+	})
+	reporter.SetOutputSecrets(OutputSecretsFail)
+
+	assert.NoError(t, reporter.Fire(&log.Entry{Message: "job succeeded", Data: map[string]interface{}{
+		"stage":     "Post",
+		"jobResult": "success",
+	}}))
+	// the state is reported by the daemon before the outputs are set, the result isn't reported yet
+	require.NoError(t, reporter.ReportState())
+	reporter.SetOutputs(map[string]string{"leaked": "mysecret"})
+	require.NoError(t, reporter.Close(""))
+
+	assert.Equal(t, []runnerv1.Result{runnerv1.Result_RESULT_UNSPECIFIED, runnerv1.Result_RESULT_FAILURE}, results)
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SummaryReader opens the file of $GITHUB_STEP_SUMMARY, which contains the summary written by the step which has just finished.
+// It returns an error satisfying errors.Is(err, fs.ErrNotExist) if there is no such file.
+type SummaryReader func(ctx context.Context) (io.ReadCloser, error)
+
+type stepSummary struct {
+	step      int
+	content   string
+	truncated bool
+}
+
+// SetSummaryReader sets the reader of the step summaries, they are reported at the end of the job.
+// The summary of a step is truncated to maxSize bytes.
+func (r *Reporter) SetSummaryReader(read SummaryReader, maxSize int64) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.summaryReader = read
+	r.summaryMaxSize = maxSize
+}
+
+// collectSummary reads the summary of the step which has just finished.
+// It's called before the next step starts, since the file is truncated for every step.
+func (r *Reporter) collectSummary(step int) {
+	r.stateMu.RLock()
+	read, maxSize := r.summaryReader, r.summaryMaxSize
+	r.stateMu.RUnlock()
+	if read == nil {
+		return
+	}
+
+	content, truncated, err := readSummary(r.ctx, read, maxSize)
+	if err != nil {
+		log.WithError(err).Warnf("failed to read the summary of step %d of task %d", step, r.state.Id)
+		return
+	}
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	r.summaries = append(r.summaries, stepSummary{
+		step:      step,
+		content:   content,
+		truncated: truncated,
+	})
+}
+
+func readSummary(ctx context.Context, read SummaryReader, maxSize int64) (string, bool, error) {
+	rc, err := read(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxSize+1))
+	if err != nil {
+		return "", false, err
+	}
+	if int64(len(content)) > maxSize {
+		return strings.ToValidUTF8(string(content[:maxSize]), ""), true, nil
+	}
+	return string(content), false, nil
+}
+
+// summarizeSteps returns the log rows of the step summaries, as a group.
+// It returns nothing if no step has written a summary.
+func (r *Reporter) summarizeSteps() []*runnerv1.LogRow {
+	if len(r.summaries) == 0 {
+		return nil
+	}
+
+	lines := []string{"::group::Job summary"}
+	for _, s := range r.summaries {
+		lines = append(lines, "--- "+r.stepName(s.step)+" ---")
+		lines = append(lines, strings.Split(strings.TrimRight(s.content, "\r\n"), "\n")...)
+		if s.truncated {
+			lines = append(lines, "(the summary is truncated, it's larger than the limit)")
+		}
+	}
+	lines = append(lines, "::endgroup::")
+
+	rows := make([]*runnerv1.LogRow, 0, len(lines))
+	for _, line := range lines {
+		rows = append(rows, &runnerv1.LogRow{
+			Time:    timestamppb.Now(),
+			Content: r.masker.Replace(strings.TrimRight(line, "\r")),
+		})
+	}
+	return rows
+}
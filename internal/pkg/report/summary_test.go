@@ -0,0 +1,108 @@
+// Copyright 2026 The Gitea Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestReadSummary(t *testing.T) {
+	reader := func(content string) SummaryReader {
+		return func(context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		}
+	}
+
+	content, truncated, err := readSummary(context.Background(), reader("# Report"), 8)
+	require.NoError(t, err)
+	assert.Equal(t, "# Report", content)
+	assert.False(t, truncated)
+
+	content, truncated, err = readSummary(context.Background(), reader("# Report"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "# Rep", content)
+	assert.True(t, truncated)
+
+	// a multi-byte character isn't cut in half
+	content, truncated, err = readSummary(context.Background(), reader("ab✓"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, "ab", content)
+	assert.True(t, truncated)
+
+	content, truncated, err = readSummary(context.Background(), func(context.Context) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("no container: %w", fs.ErrNotExist)
+	}, 5)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	assert.False(t, truncated)
+}
+
+func TestReporter_summarizeSteps(t *testing.T) {
+	taskCtx, err := structpb.NewStruct(map[string]interface{}{})
+	require.NoError(t, err)
+	r := NewReporter(context.Background(), func() {}, nil, &runnerv1.Task{
+		Context: taskCtx,
+		Secrets: map[string]string{"TOKEN": "mysecret"},
+	})
+	r.ResetSteps(3)
+	r.SetStepNames([]string{"Checkout", "Test", "Deploy"})
+
+	// the file is truncated by act before every step which runs
+	summaries := map[int]string{
+		0: "",
+		1: "## Tests\r\n\r\n42 passed with token mysecret\n",
+		2: "## Deploy\nto production",
+	}
+	var reads []int
+	current := 0
+	r.SetSummaryReader(func(context.Context) (io.ReadCloser, error) {
+		reads = append(reads, current)
+		return io.NopCloser(strings.NewReader(summaries[current])), nil
+	}, 16)
+	assert.Empty(t, r.summarizeSteps())
+
+	for step, result := range []string{"success", "failure", "skipped"} {
+		current = step
+		require.NoError(t, r.Fire(&log.Entry{Message: "done", Data: log.Fields{
+			"stage":      "Main",
+			"stepNumber": step,
+			"stepResult": result,
+		}}))
+	}
+	assert.Equal(t, []int{0, 1}, reads)
+
+	var got []string
+	for _, row := range r.summarizeSteps() {
+		got = append(got, row.Content)
+	}
+	assert.Equal(t, []string{
+		"::group::Job summary",
+		"--- step 2 (Test) ---",
+		"## Tests",
+		"",
+		"42 p",
+		"(the summary is truncated, it's larger than the limit)",
+		"::endgroup::",
+	}, got)
+
+	r.SetSummaryReader(func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("token mysecret")), nil
+	}, 1024)
+	r.collectSummary(2)
+	rows := r.summarizeSteps()
+	require.Len(t, rows, 9)
+	assert.Equal(t, "--- step 3 (Deploy) ---", rows[6].Content)
+	assert.Equal(t, "token ***", rows[7].Content)
+}